@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"movie-api/internal/data"
+	"movie-api/internal/validators"
+	"sort"
+	"strings"
+)
+
+func (adm *admin) createUser(fs *flag.FlagSet, args []string) error {
+	user := &data.User{}
+	var password string
+	var perms []string
+
+	fs.StringVar(&user.Name, "name", "", "Name")
+	fs.StringVar(&user.Email, "email", "", "Email")
+	fs.StringVar(&password, "password", "", "Password, asked for when not given")
+	fs.BoolVar(&user.Activated, "activated", false, "Create the user activated, without sending an activation token")
+	fs.Func("perm", "Permission to grant, can be repeated or comma separated", func(value string) error {
+		perms = append(perms, strings.Split(value, ",")...)
+		return nil
+	})
+
+	_, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if password == "" {
+		password, err = adm.prompt("Password")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = user.Password.Set(password)
+	if err != nil {
+		return err
+	}
+
+	v := validators.New()
+	if data.ValidateUser(v, user); !v.IsValid() {
+		return validationError(v.Errors)
+	}
+
+	if len(perms) != 0 {
+		err = adm.checkPermissions(perms)
+		if err != nil {
+			return err
+		}
+	}
+
+	if adm.dryRun {
+		adm.printf("would create %s <%s>, activated: %t, permissions: %s", user.Name, user.Email, user.Activated, listOrNone(perms))
+		return nil
+	}
+
+	err = adm.models.Users.InsertUser(user)
+	if err != nil {
+		if errors.Is(err, data.ErrDuplicateEmail) {
+			return errors.New("a user with this email address already exists")
+		}
+		return err
+	}
+
+	adm.printf("created user %d %s <%s>, activated: %t", user.ID, user.Name, user.Email, user.Activated)
+
+	if len(perms) != 0 {
+		err = adm.models.Permissions.AddForUser(user.ID, perms...)
+		if err != nil {
+			return err
+		}
+
+		adm.printf("granted %s", strings.Join(perms, ", "))
+	}
+
+	return nil
+}
+
+func (adm *admin) grantPermissions(fs *flag.FlagSet, args []string) error {
+	email := fs.String("email", "", "Email of the user")
+
+	codes, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	user, err := adm.userByEmail(*email)
+	if err != nil {
+		return err
+	}
+
+	err = adm.checkPermissions(codes)
+	if err != nil {
+		return err
+	}
+
+	if adm.dryRun {
+		adm.printf("would grant %s to %s", strings.Join(codes, ", "), user.Email)
+		return nil
+	}
+
+	err = adm.models.Permissions.AddForUser(user.ID, codes...)
+	if err != nil {
+		return err
+	}
+
+	adm.printf("granted %s to %s", strings.Join(codes, ", "), user.Email)
+	return nil
+}
+
+func (adm *admin) revokePermissions(fs *flag.FlagSet, args []string) error {
+	email := fs.String("email", "", "Email of the user")
+
+	codes, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	user, err := adm.userByEmail(*email)
+	if err != nil {
+		return err
+	}
+
+	err = adm.checkPermissions(codes)
+	if err != nil {
+		return err
+	}
+
+	if adm.dryRun {
+		adm.printf("would revoke %s from %s", strings.Join(codes, ", "), user.Email)
+		return nil
+	}
+
+	err = adm.confirm("Revoking " + strings.Join(codes, ", ") + " from " + user.Email)
+	if err != nil {
+		return err
+	}
+
+	removed, err := adm.models.Permissions.RemoveForUser(user.ID, codes...)
+	if err != nil {
+		return err
+	}
+
+	adm.printf("revoked %d permissions from %s", removed, user.Email)
+	return nil
+}
+
+func (adm *admin) pruneTokens(fs *flag.FlagSet, args []string) error {
+	_, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	count, err := adm.models.Tokens.CountExpired()
+	if err != nil {
+		return err
+	}
+
+	if adm.dryRun || count == 0 {
+		adm.printf("%d expired tokens to delete", count)
+		return nil
+	}
+
+	err = adm.confirm("Deleting the expired tokens")
+	if err != nil {
+		return err
+	}
+
+	deleted, err := adm.models.Tokens.DeleteExpired()
+	if err != nil {
+		return err
+	}
+
+	adm.printf("deleted %d expired tokens", deleted)
+	return nil
+}
+
+func (adm *admin) reindexMovies(fs *flag.FlagSet, args []string) error {
+	concurrently := fs.Bool("concurrently", true, "Rebuild without blocking writes to movies")
+
+	_, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	indexes, err := adm.movies.MovieIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	if adm.dryRun {
+		adm.printf("would rebuild %s and analyze movies", listOrNone(indexes))
+		return nil
+	}
+
+	action := "Rebuilding the indexes of movies"
+	if !*concurrently {
+		action += ", which blocks writes to movies until it's done"
+	}
+
+	err = adm.confirm(action)
+	if err != nil {
+		return err
+	}
+
+	err = adm.movies.ReindexMovies(ctx, *concurrently)
+	if err != nil {
+		return err
+	}
+
+	adm.printf("rebuilt %s and analyzed movies", listOrNone(indexes))
+	return nil
+}
+
+// validationError reports the invalid fields of an input, sorted by field
+func validationError(errs map[string]string) error {
+	var fields []string
+	for field, message := range errs {
+		fields = append(fields, field+": "+message)
+	}
+	sort.Strings(fields)
+
+	return errors.New("invalid input, " + strings.Join(fields, "; "))
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+
+	return strings.Join(values, ", ")
+}
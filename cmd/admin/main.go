@@ -0,0 +1,225 @@
+// admin runs the maintenance tasks that aren't exposed over HTTP straight against the database: creating
+// users like the first admin, granting and revoking permissions, pruning expired tokens and reindexing.
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"movie-api/internal/data"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is a subcommand, named with its group like "perm grant"
+type command struct {
+	usage       string
+	destructive bool
+	run         func(adm *admin, fs *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]command{
+	"user create":    {usage: "user create -name NAME -email EMAIL [-password PASSWORD] [-activated] [-perm CODE]...", run: (*admin).createUser},
+	"perm grant":     {usage: "perm grant -email EMAIL CODE...", run: (*admin).grantPermissions},
+	"perm revoke":    {usage: "perm revoke -email EMAIL CODE...", destructive: true, run: (*admin).revokePermissions},
+	"tokens prune":   {usage: "tokens prune", destructive: true, run: (*admin).pruneTokens},
+	"movies reindex": {usage: "movies reindex [-concurrently=false]", destructive: true, run: (*admin).reindexMovies},
+}
+
+type admin struct {
+	db     *sql.DB
+	models data.Models
+	movies data.MovieModel
+
+	// dryRun reports what a command would do without writing anything
+	dryRun bool
+	// yes skips the confirmation of destructive commands
+	yes bool
+
+	stdin  *bufio.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "admin:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr, fs) }
+
+	dsn := fs.String("db-dns", os.Getenv("MOVIES_DB"), "Database connection link")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return errors.New("no command given")
+	}
+
+	name := fs.Arg(0) + " " + fs.Arg(1)
+	cmd, ok := commands[name]
+	if !ok {
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	adm := &admin{
+		stdin:  bufio.NewReader(stdin),
+		stdout: stdout,
+		stderr: stderr,
+	}
+
+	cmdFlags := flag.NewFlagSet("admin "+name, flag.ContinueOnError)
+	cmdFlags.SetOutput(stderr)
+	cmdFlags.BoolVar(&adm.dryRun, "dry-run", false, "Print what would be done without changing anything")
+	if cmd.destructive {
+		cmdFlags.BoolVar(&adm.yes, "yes", false, "Don't ask for confirmation")
+	}
+	cmdFlags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: admin "+cmd.usage)
+		cmdFlags.PrintDefaults()
+	}
+
+	adm.db, err = data.OpenDB(*dsn)
+	if err != nil {
+		return fmt.Errorf("connecting to the database: %w", err)
+	}
+	defer adm.db.Close()
+
+	adm.models = data.NewModels(adm.db)
+	adm.movies = data.MovieModel{DB: adm.db}
+
+	return cmd.run(adm, cmdFlags, fs.Args()[2:])
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: admin [-db-dns DSN] COMMAND [-dry-run] [-yes]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "flags:")
+	fs.PrintDefaults()
+}
+
+// parse parses the flags of a subcommand, which may come after its positional arguments, and returns the
+// positional ones
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// printf writes a line of the report of a command, marked as a plan on dry runs
+func (adm *admin) printf(format string, args ...any) {
+	if adm.dryRun {
+		format = "[dry run] " + format
+	}
+
+	fmt.Fprintf(adm.stdout, format+"\n", args...)
+}
+
+// confirm asks before a destructive change, -yes answers for the user
+func (adm *admin) confirm(action string) error {
+	if adm.yes {
+		return nil
+	}
+
+	fmt.Fprintf(adm.stderr, "%s. Are you sure? [y/N] ", action)
+
+	answer, err := adm.stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+		return errors.New("aborted")
+	}
+
+	return nil
+}
+
+// prompt asks for a value on stdin
+func (adm *admin) prompt(label string) (string, error) {
+	fmt.Fprint(adm.stderr, label+": ")
+
+	line, err := adm.stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// checkPermissions fails on the codes that aren't permissions
+func (adm *admin) checkPermissions(codes []string) error {
+	if len(codes) == 0 {
+		return errors.New("no permission given")
+	}
+
+	known, err := adm.models.Permissions.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		if !known.Include(code) {
+			return fmt.Errorf("unknown permission %q, must be one of %s", code, strings.Join(known, ", "))
+		}
+	}
+
+	return nil
+}
+
+// userByEmail finds the user a permission command is about
+func (adm *admin) userByEmail(email string) (*data.User, error) {
+	if email == "" {
+		return nil, errors.New("-email is required")
+	}
+
+	user, err := adm.models.Users.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no user with the email %s", email)
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
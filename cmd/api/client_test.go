@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"movie-api/internal/data"
+	"movie-api/internal/validators"
+	"movie-api/pkg/client"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testToken = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// newClientServer serves the API over two movies to a user holding testToken with the movie permissions
+func newClientServer(t *testing.T, configure func(app *application)) *httptest.Server {
+	t.Helper()
+
+	models := data.NewMovieMockModel(
+		&data.Movies{ID: 1, Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}, Version: 1},
+		&data.Movies{ID: 2, Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror", "sci-fi"}, Version: 1},
+	)
+
+	user := &data.User{ID: 1, Name: "Tester", Email: "tester@example.com", Activated: true}
+	models.Users = data.UserMockModel{Users: []*data.User{user}, Tokens: map[string]*data.User{testToken: user}}
+	models.Permissions = data.PermissionMockModel{Permissions: map[int64]data.Permissions{user.ID: {"movies:read", "movies:write"}}}
+
+	app := newTestApplication(models)
+	if configure != nil {
+		configure(app)
+	}
+
+	srv := httptest.NewServer(app.routes())
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newClient(t *testing.T, srv *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	c, err := client.New(srv.URL, append([]client.Option{client.WithHTTPClient(srv.Client())}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestClientMovies(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newClientServer(t, nil), client.WithToken(testToken))
+
+	movies, metadata, err := c.Movies.List(ctx, client.MovieFilters{Sort: "title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	for _, movie := range movies {
+		titles = append(titles, movie.Title)
+	}
+
+	if !slices.Equal(titles, []string{"Alien", "Heat"}) || metadata.TotalRecords != 2 {
+		t.Errorf("List: got %v of %d, want [Alien Heat] of 2", titles, metadata.TotalRecords)
+	}
+
+	movie, err := c.Movies.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if movie.ID != 1 || movie.Title != "Heat" || movie.Runtime != 170 || movie.Version != 1 {
+		t.Errorf("Get: got %+v", movie)
+	}
+
+	created, err := c.Movies.Create(ctx, client.MovieInput{Title: "Ran", Year: 1999, Runtime: 162, Genres: []string{"drama"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.ID != 3 || created.Title != "Ran" || created.Version != 1 {
+		t.Errorf("Create: got %+v", created)
+	}
+
+	title := "Heat (1995)"
+
+	updated, err := c.Movies.Update(ctx, 1, client.MovieUpdate{Title: &title, Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.Title != title || updated.Version != 2 || updated.Year != 1995 {
+		t.Errorf("Update: got %+v", updated)
+	}
+
+	_, err = c.Movies.Update(ctx, 1, client.MovieUpdate{Title: &title, Version: 1})
+	if !client.IsConflict(err) {
+		t.Errorf("Update at a stale version: got %v, want a conflict", err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		format string
+		token  string
+		call   func(c *client.Client) error
+		status int
+		code   string
+		fields map[string]string
+	}{
+		{
+			name:   "not found",
+			format: errorFormatProblem,
+			token:  testToken,
+			call:   func(c *client.Client) error { _, err := c.Movies.Get(ctx, 99); return err },
+			status: http.StatusNotFound,
+			code:   "not_found",
+		},
+		{
+			name:   "anonymous",
+			format: errorFormatProblem,
+			call:   func(c *client.Client) error { _, _, err := c.Movies.List(ctx, client.MovieFilters{}); return err },
+			status: http.StatusUnauthorized,
+			code:   "authentication_required",
+		},
+		{
+			name:   "validation",
+			format: errorFormatProblem,
+			token:  testToken,
+			call: func(c *client.Client) error {
+				_, err := c.Movies.Create(ctx, client.MovieInput{Year: 1999, Runtime: 162, Genres: []string{"drama", "drama"}}, "")
+				return err
+			},
+			status: http.StatusUnprocessableEntity,
+			code:   "validation_failed",
+			fields: map[string]string{"title": validators.CodeRequired, "genres": validators.CodeDuplicate},
+		},
+		{
+			name:   "legacy not found",
+			format: errorFormatLegacy,
+			token:  testToken,
+			call:   func(c *client.Client) error { _, err := c.Movies.Get(ctx, 99); return err },
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "legacy validation",
+			format: errorFormatLegacy,
+			token:  testToken,
+			call: func(c *client.Client) error {
+				_, err := c.Movies.Create(ctx, client.MovieInput{Year: 1999, Runtime: 162, Genres: []string{"drama"}}, "")
+				return err
+			},
+			status: http.StatusExpectationFailed,
+			fields: map[string]string{"title": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newClientServer(t, func(app *application) { app.config.errorFormat = tt.format })
+			c := newClient(t, srv, client.WithToken(tt.token))
+
+			var apiErr *client.Error
+			if err := tt.call(c); !errors.As(err, &apiErr) {
+				t.Fatalf("got %v, want a *client.Error", err)
+			}
+
+			if apiErr.StatusCode != tt.status || apiErr.Code != tt.code {
+				t.Errorf("got status %d and code %q, want %d and %q", apiErr.StatusCode, apiErr.Code, tt.status, tt.code)
+			}
+
+			if len(tt.fields) == 0 && apiErr.Detail == "" {
+				t.Errorf("got no detail")
+			}
+
+			if len(apiErr.Fields) != len(tt.fields) || client.IsValidation(apiErr) != (len(tt.fields) != 0) {
+				t.Errorf("fields: got %+v, want %v", apiErr.Fields, tt.fields)
+			}
+
+			for _, field := range apiErr.Fields {
+				if code, ok := tt.fields[field.Field]; !ok || field.Code != code || field.Message == "" {
+					t.Errorf("field %+v: want code %q", field, code)
+				}
+			}
+		})
+	}
+}
+
+func TestClientRetriesRateLimited(t *testing.T) {
+	ctx := context.Background()
+
+	srv := newClientServer(t, func(app *application) {
+		app.config.limiter.rps = 1
+		app.config.limiter.burst = 1
+	})
+
+	if _, err := newClient(t, srv, client.WithToken(testToken)).Movies.Get(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := newClient(t, srv, client.WithToken(testToken), client.WithRetries(0, time.Millisecond)).Movies.Get(ctx, 1)
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Code != "rate_limit_exceeded" {
+		t.Fatalf("without retries: got %v, want a 429", err)
+	}
+
+	// the server asks for a second, which is longer than the backoff the client would pick itself
+	start := time.Now()
+
+	movie, err := newClient(t, srv, client.WithToken(testToken), client.WithRetries(1, time.Millisecond)).Movies.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("with a retry: %v", err)
+	}
+
+	if movie.ID != 1 {
+		t.Errorf("with a retry: got movie %d, want 1", movie.ID)
+	}
+
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("with a retry: done after %s, want the Retry-After second waited", elapsed)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"movie-api/internal/data"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// movieETag is the strong entity tag of a movie as its plain JSON document. It changes with every version, and
+// with the rating aggregates, which reviews change without making a new version.
+func movieETag(m *data.Movies) string {
+	return fmt.Sprintf(`"%d-%d-%d-%s"`, m.ID, m.Version, m.RatingCount, strconv.FormatFloat(m.AverageRating, 'f', -1, 64))
+}
+
+// movieVersionETag is the tag of a movie at its version, which a client can build from the id and version
+// alone. Writes accept it next to movieETag since a review coming in doesn't conflict with an edit.
+func movieVersionETag(m *data.Movies) string {
+	return fmt.Sprintf(`"%d-%d"`, m.ID, m.Version)
+}
+
+// movieRepresentationETag is the entity tag of the movie representation a GET writes. The plain JSON document
+// has the tag of the movie, the one If-Match compares to. Fieldsets, includes, runtime formats and the other
+// media types write different bytes, so they get a tag of their own hashed from the document and media type.
+func movieRepresentationETag(w http.ResponseWriter, m *data.Movies, plain bool) (string, error) {
+	// negotiating an empty object finds the media type a single movie is written in
+	mediaType, _, _ := encodeResponse(acceptHeader(w), http.StatusOK, []byte("{}"))
+
+	if plain && mediaType == "application/json" {
+		return movieETag(m), nil
+	}
+
+	js, err := json.Marshal(envelope{"movie": m})
+	if err != nil {
+		return "", err
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(mediaType))
+	h.Write(js)
+
+	return fmt.Sprintf(`"%d-%x"`, m.ID, h.Sum64()), nil
+}
+
+// etagMatches tells whether an If-Match or If-None-Match header lists the entity tag. Weak tags
+// only match when weak is set, which is the comparison If-None-Match uses.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified answers a GET with 304 when the client's copy is still current, it returns whether it did
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagMatches(header, etag, true) {
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// checkIfMatch enforces the If-Match header of a write against the current entity tags of the resource. It
+// writes the error response and returns false when the request must stop there.
+func (app *application) checkIfMatch(w http.ResponseWriter, r *http.Request, etags ...string) bool {
+	header := r.Header.Get("If-Match")
+
+	switch {
+	case header == "" && app.config.conditional.requireIfMatch:
+		app.preconditionRequiredResponse(w, r)
+		return false
+	case header != "" && !slices.ContainsFunc(etags, func(etag string) bool { return etagMatches(header, etag, false) }):
+		app.preconditionFailedResponse(w, r)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"movie-api/internal/data"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMovieETag(t *testing.T) {
+	movie := &data.Movies{ID: 7, Title: "Heat", Runtime: 170, Version: 3, AverageRating: 8.5, RatingCount: 12}
+
+	reviewed := *movie
+	reviewed.AverageRating, reviewed.RatingCount = 8.25, 13
+
+	if movieETag(movie) == movieETag(&reviewed) {
+		t.Errorf("a review left the tag at %s", movieETag(movie))
+	}
+
+	etag := func(accept string, m *data.Movies, plain bool) string {
+		t.Helper()
+
+		w := &negotiatedWriter{ResponseWriter: httptest.NewRecorder(), accept: accept}
+
+		tag, err := movieRepresentationETag(w, m, plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tag
+	}
+
+	if got := etag("", movie, true); got != movieETag(movie) {
+		t.Errorf("plain JSON: got %s, want the movie tag %s", got, movieETag(movie))
+	}
+
+	if got := etag("*/*", movie, true); got != movieETag(movie) {
+		t.Errorf("*/*: got %s, want the movie tag %s", got, movieETag(movie))
+	}
+
+	formatted := *movie
+	formatted.SetRuntimeFormat(data.RuntimeHuman)
+
+	variants := map[string]string{
+		"xml":           etag("application/xml", movie, true),
+		"msgpack":       etag("application/msgpack", movie, true),
+		"runtime":       etag("", &formatted, false),
+		"plain as json": movieETag(movie),
+	}
+
+	seen := make(map[string]string)
+	for name, tag := range variants {
+		if other, ok := seen[tag]; ok {
+			t.Errorf("%s and %s share the tag %s", name, other, tag)
+		}
+		seen[tag] = name
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	app := newTestApplication(data.NewMovieMockModel())
+	movie := &data.Movies{ID: 7, Version: 3, AverageRating: 8.5, RatingCount: 12}
+
+	tests := []struct {
+		ifMatch string
+		ok      bool
+	}{
+		{"", true},
+		{movieETag(movie), true},
+		{`"7-3"`, true},
+		{`"1-1", "7-3"`, true},
+		{"*", true},
+		{`"7-2"`, false},
+		{`"7-3-11-8.5"`, false},
+		{`W/"7-3"`, false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPatch, "/v1/movies/7", nil)
+		if tt.ifMatch != "" {
+			r.Header.Set("If-Match", tt.ifMatch)
+		}
+
+		w := httptest.NewRecorder()
+		if ok := app.checkIfMatch(w, r, movieETag(movie), movieVersionETag(movie)); ok != tt.ok {
+			t.Errorf("If-Match %s: got %t, want %t", tt.ifMatch, ok, tt.ok)
+		}
+
+		if !tt.ok && w.Code != http.StatusPreconditionFailed {
+			t.Errorf("If-Match %s: got status %d, want %d", tt.ifMatch, w.Code, http.StatusPreconditionFailed)
+		}
+	}
+}
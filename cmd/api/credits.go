@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"movie-api/internal/data"
+	"movie-api/internal/validators"
+	"net/http"
+)
+
+func (app *application) createMovieCreditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.getId(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		PersonID  int64  `json:"person_id"`
+		Role      string `json:"role"`
+		Character string `json:"character"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.readBodyErrorResponse(w, r, err)
+		return
+	}
+
+	credit := &data.Credit{
+		MovieID:   id,
+		PersonID:  input.PersonID,
+		Role:      input.Role,
+		Character: input.Character,
+	}
+
+	v := validators.New()
+	if data.ValidateCredit(v, credit); !v.IsValid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.models.Credits.Insert(credit)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCredit):
+			v.AddErr("person_id", validators.CodeDuplicate, "this person already has this credit on the movie")
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"credit": credit}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMovieCreditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.getId(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	creditID, err := app.getParam(r, "credit_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Credits.Delete(id, creditID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "credit successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
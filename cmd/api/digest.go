@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"movie-api/internal/data"
+	"strconv"
+	"time"
+)
+
+// maxDigestMovies caps the amount of movies listed in a single digest email
+const maxDigestMovies = 50
+
+// runDigests wakes up every digest interval and sends the digests that are due until stop is closed. It counts
+// in app.wg, so the shutdown waits for a digest pass that has started instead of cutting it off halfway.
+func (app *application) runDigests(stop <-chan struct{}) {
+	defer app.wg.Done()
+
+	ticker := time.NewTicker(app.config.digest.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := app.sendDigests(time.Now())
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}
+	}
+}
+
+// sendDigests emails every due recipient the movies inserted in their followed genres since their last digest.
+// The digest is claimed in the database before the email is queued, so a restart never sends it twice.
+func (app *application) sendDigests(now time.Time) error {
+	recipients, err := app.models.Preferences.GetDue(now)
+	if err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		movies, err := app.digestMovies(&recipient.Preferences, now)
+		if err != nil {
+			return err
+		}
+
+		err = app.models.Preferences.ClaimDigest(&recipient.Preferences, now)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				continue
+			default:
+				return err
+			}
+		}
+
+		if len(movies) == 0 {
+			continue
+		}
+
+		email := recipient.Email
+		templateData := map[string]any{
+			"name":      recipient.Name,
+			"frequency": recipient.Frequency,
+			"movies":    movies,
+		}
+
+		app.background(func() {
+			err := app.mailer.Send(email, "movie_digest.tmpl", templateData)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"digest_recipient": email,
+				})
+			}
+		})
+
+		app.logger.PrintInfo("digest queued", map[string]string{
+			"user_id": strconv.FormatInt(recipient.UserID, 10),
+			"movies":  strconv.Itoa(len(movies)),
+		})
+	}
+
+	return nil
+}
+
+// digestMovies walks the newest movies of every followed genre through GetAllMovies until it reaches
+// movies older than the last digest. Movies inserted after until are left for the next digest.
+func (app *application) digestMovies(p *data.Preferences, until time.Time) ([]*data.Movies, error) {
+	since := p.Since()
+	seen := make(map[int64]bool)
+	var movies []*data.Movies
+
+	for _, genre := range p.Genres {
+		filters := data.Filters{
+			Page:      1,
+			PageSize:  100,
+			Sort:      "-id",
+			SortList:  []string{"-id"},
+			SkipTotal: true,
+		}
+
+		for {
+			page, _, err := app.models.Movies.GetAllMovies(data.MovieQuery{Genres: []string{genre}}, filters)
+			if err != nil {
+				return nil, err
+			}
+
+			done := len(page) < filters.PageSize
+
+			for _, movie := range page {
+				if !movie.CreatedAt.After(since) {
+					done = true
+					break
+				}
+
+				if movie.CreatedAt.After(until) || seen[movie.ID] {
+					continue
+				}
+
+				seen[movie.ID] = true
+				movies = append(movies, movie)
+
+				if len(movies) == maxDigestMovies {
+					return movies, nil
+				}
+			}
+
+			if done {
+				break
+			}
+
+			filters.Page++
+		}
+	}
+
+	return movies, nil
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"movie-api/internal/msgpack"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	errUnsupportedMediaType = errors.New("unsupported media type")
+	errNotAcceptable        = errors.New("not acceptable")
+
+	// errNotTabular is returned by encoders that can only write lists, like CSV
+	errNotTabular = errors.New("response is not a list")
+)
+
+// responseEncoder turns the JSON encoding of an envelope into another representation
+type responseEncoder struct {
+	mediaType string
+	aliases   []string
+	encode    func(js []byte) ([]byte, error)
+}
+
+// responseEncoders are the representations responses can be negotiated to, in the order the server prefers them
+var responseEncoders = []responseEncoder{
+	{mediaType: "application/json", encode: func(js []byte) ([]byte, error) { return js, nil }},
+	{mediaType: "application/xml", aliases: []string{"text/xml"}, encode: jsonToXML},
+	{mediaType: "text/csv", encode: jsonToCSV},
+	{mediaType: "application/msgpack", aliases: []string{"application/x-msgpack"}, encode: msgpack.FromJSON},
+}
+
+// negotiatedWriter carries the Accept header of the request down to writeJSON
+type negotiatedWriter struct {
+	http.ResponseWriter
+	accept string
+}
+
+func (nw *negotiatedWriter) Unwrap() http.ResponseWriter {
+	return nw.ResponseWriter
+}
+
+// negotiateContent lets the responses of the request be written in the representation its Accept header asks for
+func (app *application) negotiateContent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&negotiatedWriter{ResponseWriter: w, accept: r.Header.Get("Accept")}, r)
+	})
+}
+
+// acceptHeader finds the Accept header of the request the writer answers, going through the writers wrapping it
+func acceptHeader(w http.ResponseWriter) string {
+	for {
+		switch rw := w.(type) {
+		case *negotiatedWriter:
+			return rw.accept
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return ""
+		}
+	}
+}
+
+// acceptedEncoders lists the encoders an Accept header allows, the most preferred first. Media ranges are
+// ranked by quality, a tie going to the more specific range and then to the order of the header.
+func acceptedEncoders(accept string) []responseEncoder {
+	if strings.TrimSpace(accept) == "" {
+		return responseEncoders[:1]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].quality != ranges[j].quality {
+			return ranges[i].quality > ranges[j].quality
+		}
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	var encoders []responseEncoder
+
+	for _, r := range ranges {
+		for _, encoder := range responseEncoders {
+			types := append([]string{encoder.mediaType}, encoder.aliases...)
+
+			for _, mediaType := range types {
+				mainType, _, _ := strings.Cut(mediaType, "/")
+
+				if r.mediaType == mediaType || r.mediaType == "*/*" || r.mediaType == mainType+"/*" {
+					encoders = append(encoders, encoder)
+					break
+				}
+			}
+		}
+	}
+
+	return encoders
+}
+
+// encodeResponse writes the JSON encoding of an envelope in the first accepted representation able to
+// hold it. Errors fall back to JSON when the client accepts nothing that can represent them.
+func encodeResponse(accept string, status int, js []byte) (string, []byte, error) {
+	for _, encoder := range acceptedEncoders(accept) {
+		body, err := encoder.encode(js)
+		if errors.Is(err, errNotTabular) {
+			continue
+		}
+
+		return encoder.mediaType, body, err
+	}
+
+	if status >= http.StatusBadRequest {
+		return "application/json", js, nil
+	}
+
+	return "", nil, errNotAcceptable
+}
+
+func (app *application) notAcceptableBody() []byte {
+	var types []string
+	for _, encoder := range responseEncoders {
+		types = append(types, encoder.mediaType)
+	}
+
+	message := fmt.Sprintf("the resource cannot be represented in the requested media types, it's available as %s", strings.Join(types, ", "))
+
+	if app.config.errorFormat == errorFormatLegacy {
+		js, _ := json.Marshal(envelope{"error": message})
+		return js
+	}
+
+	js, _ := json.Marshal(newProblem(nil, http.StatusNotAcceptable, "not_acceptable", message))
+	return js
+}
+
+// problemMediaType returns the problem details counterpart of a media type, RFC 7807 registers one for JSON
+// and XML
+func problemMediaType(mediaType string) string {
+	switch mediaType {
+	case "application/json":
+		return "application/problem+json"
+	case "application/xml":
+		return "application/problem+xml"
+	default:
+		return mediaType
+	}
+}
+
+// jsonToXML rewrites a JSON document as XML under a response element. Members become elements named after
+// their key, or entry elements with a key attribute when the key isn't a valid XML name, and array items
+// become item elements.
+func jsonToXML(js []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.UseNumber()
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+
+	err := writeXMLValue(d, enc, xml.StartElement{Name: xml.Name{Local: "response"}})
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var xmlNameRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+func writeXMLValue(d *json.Decoder, enc *xml.Encoder, start xml.StartElement) error {
+	token, err := d.Token()
+	if err != nil {
+		return err
+	}
+
+	if token == nil {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+	}
+
+	err = enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		for d.More() {
+			child := xml.StartElement{Name: xml.Name{Local: "item"}}
+
+			if token == '{' {
+				keyToken, err := d.Token()
+				if err != nil {
+					return err
+				}
+
+				key := keyToken.(string)
+				if xmlNameRx.MatchString(key) && !strings.HasPrefix(strings.ToLower(key), "xml") {
+					child.Name.Local = key
+				} else {
+					child.Name.Local = "entry"
+					child.Attr = []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}}
+				}
+			}
+
+			err = writeXMLValue(d, enc, child)
+			if err != nil {
+				return err
+			}
+		}
+
+		// the closing delimiter
+		_, err = d.Token()
+		if err != nil {
+			return err
+		}
+	case nil:
+	default:
+		err = enc.EncodeToken(xml.CharData(fmt.Sprint(token)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// jsonToCSV writes the list of an envelope as CSV, one row per item and one column per member of the items.
+// Envelopes that hold no list of objects, like a single movie, aren't tabular; the metadata and other members
+// next to the list are left out. Lists of scalars are joined with |, nested objects stay JSON.
+func jsonToCSV(js []byte) ([]byte, error) {
+	var env map[string]json.RawMessage
+
+	err := json.Unmarshal(js, &env)
+	if err != nil {
+		return nil, errNotTabular
+	}
+
+	var rows []json.RawMessage
+	found := false
+
+	// an empty list is written as null
+	for _, value := range env {
+		var items []json.RawMessage
+		if json.Unmarshal(value, &items) != nil || (len(items) != 0 && !bytes.HasPrefix(bytes.TrimSpace(items[0]), []byte("{"))) {
+			continue
+		}
+
+		if found {
+			return nil, errNotTabular
+		}
+
+		rows, found = items, true
+	}
+
+	if !found {
+		return nil, errNotTabular
+	}
+
+	members := make([]map[string]json.RawMessage, len(rows))
+
+	// the header has a column for every member of any row, items leave out empty members and carry relations
+	// only some have. A member the rows before didn't have goes after the one it follows in its row.
+	var header []string
+
+	for i, row := range rows {
+		keys, values, err := orderedMembers(row)
+		if err != nil {
+			return nil, err
+		}
+
+		members[i] = values
+
+		previous := -1
+		for _, key := range keys {
+			j := slices.Index(header, key)
+			if j < 0 {
+				j = previous + 1
+				header = slices.Insert(header, j, key)
+			}
+
+			previous = j
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	w.Write(header)
+
+	for _, values := range members {
+		record := make([]string, len(header))
+		for j, key := range header {
+			record[j] = csvCell(values[key])
+		}
+
+		w.Write(record)
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// orderedMembers returns the keys of a JSON object in the order they're written along with their values
+func orderedMembers(js []byte) ([]string, map[string]json.RawMessage, error) {
+	d := json.NewDecoder(bytes.NewReader(js))
+
+	_, err := d.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keys []string
+	values := make(map[string]json.RawMessage)
+
+	for d.More() {
+		token, err := d.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var value json.RawMessage
+
+		err = d.Decode(&value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key := token.(string)
+		keys = append(keys, key)
+		values[key] = value
+	}
+
+	return keys, values, nil
+}
+
+func csvCell(value json.RawMessage) string {
+	if value == nil {
+		return ""
+	}
+
+	var scalar any
+	d := json.NewDecoder(bytes.NewReader(value))
+	d.UseNumber()
+
+	if d.Decode(&scalar) != nil {
+		return string(value)
+	}
+
+	switch scalar := scalar.(type) {
+	case nil:
+		return ""
+	case []any:
+		var items []string
+		for _, item := range scalar {
+			switch item.(type) {
+			case map[string]any, []any:
+				return string(value)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, "|")
+	case map[string]any:
+		return string(value)
+	default:
+		return fmt.Sprint(scalar)
+	}
+}
+
+// readBody reads the body of a request as JSON, converting the representations requests can be sent in.
+// Bodies of any other type fail with errUnsupportedMediaType.
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return body, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errUnsupportedMediaType
+	}
+
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return body, nil
+	case mediaType == "application/msgpack", mediaType == "application/x-msgpack":
+		js, err := msgpack.ToJSON(body)
+		if err != nil {
+			return nil, fmt.Errorf("body contains badly-formed MessagePack: %w", err)
+		}
+		return js, nil
+	default:
+		return nil, errUnsupportedMediaType
+	}
+}
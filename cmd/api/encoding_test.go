@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJSONToCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		js   string
+		want string
+	}{
+		{
+			name: "same members",
+			js:   `{"movies":[{"id":1,"title":"Heat"},{"id":2,"title":"Alien"}],"metadata":{"page":1}}`,
+			want: "id,title\n1,Heat\n2,Alien\n",
+		},
+		{
+			name: "sparse members",
+			js:   `{"movies":[{"id":1,"title":"Heat"},{"id":2,"year":1979,"title":"Alien","genres":["horror","sci-fi"]}]}`,
+			want: "id,year,title,genres\n1,,Heat,\n2,1979,Alien,horror|sci-fi\n",
+		},
+		{
+			name: "member missing from the first row",
+			js:   `{"movies":[{"id":1,"version":2},{"id":2,"title":"Alien","version":1}]}`,
+			want: "id,title,version\n1,,2\n2,Alien,1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonToCSV([]byte(tt.js))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	_, err := jsonToCSV([]byte(`{"movie":{"id":1}}`))
+	if !errors.Is(err, errNotTabular) {
+		t.Errorf("single movie: got %v, want errNotTabular", err)
+	}
+}
@@ -1,6 +1,12 @@
 package main
 
-import "net/http"
+import (
+	"errors"
+	"fmt"
+	"movie-api/internal/validators"
+	"net/http"
+	"sort"
+)
 
 func (app *application) logError(r *http.Request, err error) {
 	app.logger.PrintError(err, map[string]string{
@@ -9,56 +15,183 @@ func (app *application) logError(r *http.Request, err error) {
 	})
 }
 
-func (app *application) errorResponse(w http.ResponseWriter, status int, message any) {
-	env := envelope{"error": message}
-	app.writeJSON(w, status, env, nil)
+const (
+	errorFormatProblem = "problem"
+	errorFormatLegacy  = "legacy"
+)
+
+// problem is an RFC 7807 problem details object. Code identifies the kind of problem for programs,
+// Errors lists the fields a validation failure is about.
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []fieldError `json:"errors,omitempty"`
+}
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newProblem(r *http.Request, status int, code, detail string) problem {
+	p := problem{
+		Type:   "urn:movie-api:problem:" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+
+	return p
+}
+
+// errorResponse writes an error as problem details, or in the {"error": message} envelope with the legacy error format
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if app.config.errorFormat == errorFormatLegacy {
+		app.writeJSON(w, status, envelope{"error": message}, nil)
+		return
+	}
+
+	app.writeProblem(w, newProblem(r, status, code, message))
 }
 
+func (app *application) writeProblem(w http.ResponseWriter, p problem) {
+	app.writeResponse(w, p.Status, p, nil, true)
+}
+
+// the messages of the errors GraphQL resolvers report too
+const (
+	serverErrorMessage            = "sorry the server could not process your response"
+	authenticationRequiredMessage = "you must be authenticated to access this resource"
+	inactiveAccountMessage        = "your account must be activated to access this resource"
+	notPermittedMessage           = "your user account does not have the necessary permissions to access this resource"
+)
+
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.logError(r, err)
-	message := "sorry the server could not process your response"
-	app.errorResponse(w, http.StatusInternalServerError, envelope{"error": message})
+	message := serverErrorMessage
+	app.errorResponse(w, r, http.StatusInternalServerError, "server_error", message)
+}
+
+func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the requested resource could not be found"
+	app.errorResponse(w, r, http.StatusNotFound, "not_found", message)
+}
+
+func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, "method_not_allowed", message)
+}
+
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("the %q content type is not supported for this resource", r.Header.Get("Content-Type"))
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, "unsupported_media_type", message)
 }
 
-func (app *application) failedValidationResponse(w http.ResponseWriter, errors map[string]string) {
-	app.errorResponse(w, http.StatusExpectationFailed, errors)
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has changed since you last read it, fetch it again and retry"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, "precondition_failed", message)
+}
+
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this request must be conditional, send the ETag you read in an If-Match header"
+	app.errorResponse(w, r, http.StatusPreconditionRequired, "precondition_required", message)
+}
+
+func (app *application) idempotencyKeyReusedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the Idempotency-Key was already used for a different request"
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, "idempotency_key_reused", message)
+}
+
+func (app *application) idempotencyKeyInFlightResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+	message := "a request with this Idempotency-Key is still being processed, retry later"
+	app.errorResponse(w, r, http.StatusConflict, "idempotency_key_in_flight", message)
+}
+
+// readBodyErrorResponse answers a request whose body couldn't be read
+func (app *application) readBodyErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	app.errorResponse(w, r, http.StatusBadRequest, "bad_request", err.Error())
+}
+
+// failedValidationResponse lists every invalid field with the code of what's wrong with it, sorted by field
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, v *validators.Validators) {
+	if app.config.errorFormat == errorFormatLegacy {
+		app.writeJSON(w, http.StatusExpectationFailed, envelope{"error": v.Errors}, nil)
+		return
+	}
+
+	p := newProblem(r, http.StatusUnprocessableEntity, "validation_failed", "the request contains invalid values")
+	p.Errors = fieldErrors(v)
+
+	app.writeProblem(w, p)
+}
+
+// fieldErrors lists the messages of a validation failure with the codes they were recorded with, sorted by field
+func fieldErrors(v *validators.Validators) []fieldError {
+	var fields []fieldError
+
+	for field, message := range v.Errors {
+		code := v.Codes[field]
+		if code == "" {
+			code = validators.CodeInvalid
+		}
+
+		fields = append(fields, fieldError{Field: field, Code: code, Message: message})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+
+	return fields
 }
 
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
 	message := "rate limit exceeded"
-	app.errorResponse(w, http.StatusTooManyRequests, message)
+	app.errorResponse(w, r, http.StatusTooManyRequests, "rate_limit_exceeded", message)
 }
 
-func (app *application) editConflictResponse(w http.ResponseWriter) {
-	app.errorResponse(w, http.StatusConflict, "cannot complete your request cause an error")
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, "edit_conflict", "cannot complete your request cause an error")
 }
 
-func (app *application) badRequestResponse(w http.ResponseWriter) {
-	app.errorResponse(w, http.StatusBadRequest, "bad request error")
+func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusBadRequest, "bad_request", "bad request error")
 }
 
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid credentials for this resource"
-	app.errorResponse(w, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "invalid_credentials", message)
 }
 
 func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("WWW-Authenticate", "Bearer")
 	message := "invalid or missing authentication token"
-	app.errorResponse(w, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "invalid_authentication_token", message)
 }
 
 func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
-	message := "you must be authenticated to access this resource"
-	app.errorResponse(w, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "authentication_required", authenticationRequiredMessage)
 }
 
 func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
-	message := "your account must be activated to access this resource"
-	app.errorResponse(w, http.StatusForbidden, message)
+	app.errorResponse(w, r, http.StatusForbidden, "inactive_account", inactiveAccountMessage)
 }
 
 func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
-	message := "your user account does not have the necessary permissions to access this resource"
-	app.errorResponse(w, http.StatusForbidden, message)
+	app.errorResponse(w, r, http.StatusForbidden, "not_permitted", notPermittedMessage)
 }
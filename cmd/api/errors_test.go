@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"movie-api/internal/jsonlog"
+	"movie-api/internal/validators"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFieldErrors(t *testing.T) {
+	v := validators.New()
+	v.Check(false, "title", validators.CodeRequired, "must be provided")
+	v.Check(false, "genres", validators.CodeDuplicate, "must not contain duplicate values")
+	v.Check(false, "title", validators.CodeTooLong, "must not be more than 500 bytes long")
+	v.AddErr("year", "", "must be a year")
+
+	want := []fieldError{
+		{Field: "genres", Code: validators.CodeDuplicate, Message: "must not contain duplicate values"},
+		{Field: "title", Code: validators.CodeRequired, Message: "must be provided"},
+		{Field: "year", Code: validators.CodeInvalid, Message: "must be a year"},
+	}
+
+	got := fieldErrors(v)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("field %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLegacyServerErrorResponse(t *testing.T) {
+	app := &application{logger: jsonlog.New(io.Discard, jsonlog.LevelOff)}
+	app.config.errorFormat = errorFormatLegacy
+
+	w := httptest.NewRecorder()
+	app.serverErrorResponse(w, httptest.NewRequest(http.MethodGet, "/v1/movies", nil), errors.New("boom"))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status: got %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if message, ok := body["error"].(string); !ok || message != serverErrorMessage {
+		t.Errorf("body: got %s, want the message under \"error\"", w.Body.String())
+	}
+}
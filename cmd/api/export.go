@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"movie-api/internal/data"
+	"movie-api/internal/validators"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFlushEvery is the amount of rows written between two flushes of the response
+const exportFlushEvery = 500
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"tsv":    "text/tab-separated-values; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+}
+
+var exportHeader = []string{"id", "title", "year", "runtime", "genres", "average_rating", "rating_count", "version"}
+
+// exportMoviesHandler streams every movie matching the listing filters as a chunked response. Once the
+// first row is out the status can't change anymore, so a failure halfway is logged and cuts the stream short.
+func (app *application) exportMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validators.New()
+
+	qs := r.URL.Query()
+
+	query := app.readMovieQuery(qs, v)
+	format := app.readString(qs, "format", "csv")
+
+	data.ValidateMovieQuery(v, query)
+	v.Check(validators.PermittedValues(format, "csv", "tsv", "ndjson"), "format", validators.CodeInvalid, "must be csv, tsv or ndjson")
+
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// the export outlives the write timeout of the server, the deadline is lifted for this response only
+	rc := http.NewResponseController(w)
+	err := rc.SetWriteDeadline(time.Time{})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="movies.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	var write func(*data.Movies) error
+	var flush func() error
+
+	switch format {
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		write = func(movie *data.Movies) error { return enc.Encode(movie) }
+		flush = func() error { return nil }
+	default:
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+
+		err = cw.Write(exportHeader)
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+
+		write = func(movie *data.Movies) error {
+			return cw.Write([]string{
+				strconv.FormatInt(movie.ID, 10),
+				movie.Title,
+				strconv.Itoa(int(movie.Year)),
+				strconv.Itoa(int(movie.Runtime)),
+				strings.Join(movie.Genres, "|"),
+				strconv.FormatFloat(movie.AverageRating, 'f', 2, 64),
+				strconv.Itoa(int(movie.RatingCount)),
+				strconv.Itoa(int(movie.Version)),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	}
+
+	written := 0
+
+	err = app.models.Movies.ExportMovies(r.Context(), query, func(movie *data.Movies) error {
+		err := write(movie)
+		if err != nil {
+			return err
+		}
+
+		written++
+		if written%exportFlushEvery != 0 {
+			return nil
+		}
+
+		err = flush()
+		if err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	})
+
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,670 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"movie-api/internal/data"
+	"movie-api/internal/graphql"
+	"movie-api/internal/validators"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var graphqlContextKey = contextKey("graphql")
+
+// graphqlRequest is what the resolvers of a GraphQL request share, they reach it through the context
+type graphqlRequest struct {
+	user *data.User
+
+	// permissions are loaded by the first field that checks them
+	permissions data.Permissions
+}
+
+func graphqlRequestFrom(ctx context.Context) *graphqlRequest {
+	req, ok := ctx.Value(graphqlContextKey).(*graphqlRequest)
+	if !ok {
+		panic("missing graphql request in context")
+	}
+
+	return req
+}
+
+func (app *application) graphqlHandler(schema *graphql.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Query         string         `json:"query"`
+			OperationName string         `json:"operationName"`
+			Variables     map[string]any `json:"variables"`
+			Extensions    map[string]any `json:"extensions"`
+		}
+
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.readBodyErrorResponse(w, r, err)
+			return
+		}
+
+		v := validators.New()
+		if v.Check(input.Query != "", "query", validators.CodeRequired, "must be provided"); !v.IsValid() {
+			app.failedValidationResponse(w, r, v)
+			return
+		}
+
+		req := &graphqlRequest{user: app.contextGetUser(r)}
+
+		result := schema.Execute(graphql.Params{
+			Context:       context.WithValue(r.Context(), graphqlContextKey, req),
+			Query:         input.Query,
+			OperationName: input.OperationName,
+			Variables:     input.Variables,
+			PresentError: func(err error) *graphql.Error {
+				app.logError(r, err)
+				return graphql.NewError("server_error", serverErrorMessage)
+			},
+		})
+
+		// errors of the query are part of the result, the request itself went fine
+		err = app.writeResponse(w, http.StatusOK, result, nil, false)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// graphqlRequirePermission checks what requirePermissionResponse does for a route: an activated user
+// holding the permission
+func (app *application) graphqlRequirePermission(ctx context.Context, code string) error {
+	req := graphqlRequestFrom(ctx)
+
+	if req.user.IsAnonymous() {
+		return graphql.NewError("authentication_required", authenticationRequiredMessage)
+	}
+
+	if !req.user.Activated {
+		return graphql.NewError("inactive_account", inactiveAccountMessage)
+	}
+
+	permissions, err := app.graphqlPermissions(req)
+	if err != nil {
+		return err
+	}
+
+	if !permissions.Include(code) {
+		return graphql.NewError("not_permitted", notPermittedMessage)
+	}
+
+	return nil
+}
+
+// graphqlPermissions returns the permissions of the user, reading them once per request
+func (app *application) graphqlPermissions(req *graphqlRequest) (data.Permissions, error) {
+	if req.permissions == nil {
+		permissions, err := app.models.Permissions.GetAllForUser(req.user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		req.permissions = append(data.Permissions{}, permissions...)
+	}
+
+	return req.permissions, nil
+}
+
+// graphqlValidationError reports invalid arguments the way failedValidationResponse reports invalid fields
+func graphqlValidationError(v *validators.Validators) *graphql.Error {
+	err := graphql.NewError("validation_failed", "the request contains invalid values")
+	err.Extensions["errors"] = fieldErrors(v)
+	return err
+}
+
+func nonNull(t graphql.Type) graphql.Type {
+	return &graphql.NonNull{Of: t}
+}
+
+func listOf(t graphql.Type) graphql.Type {
+	return &graphql.List{Of: nonNull(t)}
+}
+
+// fieldOf resolves a field from the source object alone
+func fieldOf[T any](fn func(source T) any) func(p graphql.ResolveParams) (any, error) {
+	return func(p graphql.ResolveParams) (any, error) {
+		return fn(p.Source.(T)), nil
+	}
+}
+
+// optional turns empty strings into null
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+// movieID reads an ID argument of a movie, ids that can't be one come out as 0
+func movieID(v any) int64 {
+	id, ok := v.(int64)
+	if !ok || id < 1 {
+		return 0
+	}
+
+	return id
+}
+
+// graphqlSchema builds the schema of /v1/graphql over the models. The fields check the permissions of the
+// routes serving the same data, and the lists nested in movies are loaded for a whole page of movies at once.
+func (app *application) graphqlSchema() (*graphql.Schema, error) {
+	credit := &graphql.Object{
+		Name: "Credit",
+		Fields: map[string]*graphql.Field{
+			"id":          {Type: nonNull(graphql.ID), Resolve: fieldOf(func(c *data.Credit) any { return c.ID })},
+			"person_id":   {Type: nonNull(graphql.ID), Resolve: fieldOf(func(c *data.Credit) any { return c.PersonID })},
+			"person_name": {Type: nonNull(graphql.String), Resolve: fieldOf(func(c *data.Credit) any { return c.PersonName })},
+			"role":        {Type: nonNull(graphql.String), Resolve: fieldOf(func(c *data.Credit) any { return c.Role })},
+			"character":   {Type: graphql.String, Resolve: fieldOf(func(c *data.Credit) any { return optional(c.Character) })},
+		},
+	}
+
+	review := &graphql.Object{
+		Name: "Review",
+		Fields: map[string]*graphql.Field{
+			"id":         {Type: nonNull(graphql.ID), Resolve: fieldOf(func(r *data.Review) any { return r.ID })},
+			"movie_id":   {Type: nonNull(graphql.ID), Resolve: fieldOf(func(r *data.Review) any { return r.MovieID })},
+			"user_id":    {Type: nonNull(graphql.ID), Resolve: fieldOf(func(r *data.Review) any { return r.UserID })},
+			"created_at": {Type: nonNull(graphql.String), Resolve: fieldOf(func(r *data.Review) any { return r.CreatedAt.Format(time.RFC3339) })},
+			"rating":     {Type: nonNull(graphql.Int), Resolve: fieldOf(func(r *data.Review) any { return r.Rating })},
+			"body":       {Type: graphql.String, Resolve: fieldOf(func(r *data.Review) any { return optional(r.Body) })},
+			"version":    {Type: nonNull(graphql.Int), Resolve: fieldOf(func(r *data.Review) any { return r.Version })},
+		},
+	}
+
+	movie := &graphql.Object{
+		Name: "Movie",
+		Fields: map[string]*graphql.Field{
+			"id":             {Type: nonNull(graphql.ID), Resolve: fieldOf(func(m *data.Movies) any { return m.ID })},
+			"title":          {Type: nonNull(graphql.String), Resolve: fieldOf(func(m *data.Movies) any { return m.Title })},
+			"year":           {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m *data.Movies) any { return m.Year })},
+			"runtime":        {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m *data.Movies) any { return int32(m.Runtime) })},
+			"genres":         {Type: nonNull(listOf(graphql.String)), Resolve: fieldOf(func(m *data.Movies) any { return m.Genres })},
+			"average_rating": {Type: nonNull(graphql.Float), Resolve: fieldOf(func(m *data.Movies) any { return m.AverageRating })},
+			"rating_count":   {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m *data.Movies) any { return m.RatingCount })},
+			"version":        {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m *data.Movies) any { return m.Version })},
+			"credits": {
+				Type:       nonNull(listOf(credit)),
+				Batch:      app.resolveMovieCredits,
+				Multiplier: func(args map[string]any) int { return 10 },
+			},
+			"reviews": {
+				Type:       nonNull(listOf(review)),
+				Args:       map[string]*graphql.Argument{"limit": {Type: graphql.Int, Default: 10}},
+				Batch:      app.resolveMovieReviews,
+				Multiplier: func(args map[string]any) int { return intArg(args, "limit") },
+			},
+		},
+	}
+
+	metadata := &graphql.Object{
+		Name: "Metadata",
+		Fields: map[string]*graphql.Field{
+			"current_page":  {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m data.Metadata) any { return m.CurrentPage })},
+			"page_size":     {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m data.Metadata) any { return m.PageSize })},
+			"first_page":    {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m data.Metadata) any { return m.FirstPage })},
+			"last_page":     {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m data.Metadata) any { return m.LastPage })},
+			"total_records": {Type: nonNull(graphql.Int), Resolve: fieldOf(func(m data.Metadata) any { return m.TotalRecords })},
+			"next_cursor":   {Type: graphql.String, Resolve: fieldOf(func(m data.Metadata) any { return optional(m.NextCursor) })},
+			"prev_cursor":   {Type: graphql.String, Resolve: fieldOf(func(m data.Metadata) any { return optional(m.PrevCursor) })},
+		},
+	}
+
+	moviePage := &graphql.Object{
+		Name: "MoviePage",
+		Fields: map[string]*graphql.Field{
+			"movies":   {Type: nonNull(listOf(movie)), Resolve: fieldOf(func(p *moviePage) any { return p.movies })},
+			"metadata": {Type: nonNull(metadata), Resolve: fieldOf(func(p *moviePage) any { return p.metadata })},
+		},
+	}
+
+	viewer := &graphql.Object{
+		Name: "Viewer",
+		Fields: map[string]*graphql.Field{
+			"id":        {Type: nonNull(graphql.ID), Resolve: fieldOf(func(u *data.User) any { return u.ID })},
+			"name":      {Type: nonNull(graphql.String), Resolve: fieldOf(func(u *data.User) any { return u.Name })},
+			"email":     {Type: nonNull(graphql.String), Resolve: fieldOf(func(u *data.User) any { return u.Email })},
+			"activated": {Type: nonNull(graphql.Boolean), Resolve: fieldOf(func(u *data.User) any { return u.Activated })},
+			"created_at": {
+				Type:    nonNull(graphql.String),
+				Resolve: fieldOf(func(u *data.User) any { return u.CreatedAt.Format(time.RFC3339) }),
+			},
+			"permissions": {Type: nonNull(listOf(graphql.String)), Resolve: app.resolveViewerPermissions},
+		},
+	}
+
+	stringList := listOf(graphql.String)
+
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: map[string]*graphql.Field{
+			"movies": {
+				Type: nonNull(moviePage),
+				Args: map[string]*graphql.Argument{
+					"q":              {Type: graphql.String},
+					"title":          {Type: graphql.String},
+					"genres":         {Type: stringList},
+					"genres_any":     {Type: stringList},
+					"genres_exclude": {Type: stringList},
+					"year_min":       {Type: graphql.Int},
+					"year_max":       {Type: graphql.Int},
+					"runtime_min":    {Type: graphql.Int},
+					"runtime_max":    {Type: graphql.Int},
+					"created_after":  {Type: graphql.String},
+					"created_before": {Type: graphql.String},
+					"sort":           {Type: graphql.String},
+					"cursor":         {Type: graphql.String},
+					"page":           {Type: graphql.Int, Default: 1},
+					"page_size":      {Type: graphql.Int, Default: 20},
+				},
+				Resolve:    app.resolveMovies,
+				Multiplier: func(args map[string]any) int { return intArg(args, "page_size") },
+			},
+			"movie": {
+				Type:    movie,
+				Args:    map[string]*graphql.Argument{"id": {Type: nonNull(graphql.ID)}},
+				Resolve: app.resolveMovie,
+			},
+			"viewer": {
+				Type: viewer,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					user := graphqlRequestFrom(p.Context).user
+					if user.IsAnonymous() {
+						return nil, nil
+					}
+					return user, nil
+				},
+			},
+		},
+	}
+
+	movieInput := &graphql.InputObject{
+		Name: "MovieInput",
+		Fields: map[string]*graphql.Argument{
+			"title":   {Type: nonNull(graphql.String)},
+			"year":    {Type: nonNull(graphql.Int)},
+			"runtime": {Type: nonNull(graphql.Int)},
+			"genres":  {Type: nonNull(stringList)},
+		},
+	}
+
+	movieUpdateInput := &graphql.InputObject{
+		Name: "MovieUpdateInput",
+		Fields: map[string]*graphql.Argument{
+			"title":   {Type: graphql.String},
+			"year":    {Type: graphql.Int},
+			"runtime": {Type: graphql.Int},
+			"genres":  {Type: stringList},
+		},
+	}
+
+	mutation := &graphql.Object{
+		Name: "Mutation",
+		Fields: map[string]*graphql.Field{
+			"create_movie": {
+				Type:    nonNull(movie),
+				Args:    map[string]*graphql.Argument{"input": {Type: nonNull(movieInput)}},
+				Resolve: app.resolveCreateMovie,
+			},
+			"update_movie": {
+				Type: nonNull(movie),
+				Args: map[string]*graphql.Argument{
+					"id":      {Type: nonNull(graphql.ID)},
+					"input":   {Type: nonNull(movieUpdateInput)},
+					"version": {Type: graphql.Int},
+				},
+				Resolve: app.resolveUpdateMovie,
+			},
+			"delete_movie": {
+				Type: nonNull(graphql.ID),
+				Args: map[string]*graphql.Argument{
+					"id":      {Type: nonNull(graphql.ID)},
+					"version": {Type: graphql.Int},
+				},
+				Resolve: app.resolveDeleteMovie,
+			},
+		},
+	}
+
+	schema, err := graphql.NewSchema(query, mutation)
+	if err != nil {
+		return nil, err
+	}
+
+	schema.MaxDepth = app.config.graphql.maxDepth
+	schema.MaxComplexity = app.config.graphql.maxComplexity
+
+	return schema, nil
+}
+
+// moviePage is a page of the movies query
+type moviePage struct {
+	movies   []*data.Movies
+	metadata data.Metadata
+}
+
+func (app *application) resolveMovies(p graphql.ResolveParams) (any, error) {
+	err := app.graphqlRequirePermission(p.Context, "movies:read")
+	if err != nil {
+		return nil, err
+	}
+
+	v := validators.New()
+
+	q := data.MovieQuery{
+		Search:        stringArg(p.Args, "q"),
+		Title:         stringArg(p.Args, "title"),
+		Genres:        stringsArg(p.Args, "genres"),
+		GenresAny:     stringsArg(p.Args, "genres_any"),
+		GenresExclude: stringsArg(p.Args, "genres_exclude"),
+		YearMin:       int32(intArg(p.Args, "year_min")),
+		YearMax:       int32(intArg(p.Args, "year_max")),
+		RuntimeMin:    data.Runtime(intArg(p.Args, "runtime_min")),
+		RuntimeMax:    data.Runtime(intArg(p.Args, "runtime_max")),
+		CreatedAfter:  timeArg(p.Args, "created_after", v),
+		CreatedBefore: timeArg(p.Args, "created_before", v),
+	}
+
+	// searches come best match first unless asked otherwise, like on GET /v1/movies
+	defaultSort := "id"
+	if q.Search != "" {
+		defaultSort = "-score"
+	}
+
+	filters := data.Filters{
+		Page:     intArg(p.Args, "page"),
+		PageSize: intArg(p.Args, "page_size"),
+		Sort:     stringArg(p.Args, "sort"),
+		SortList: []string{"id", "title", "year", "runtime", "rating", "score", "-id", "-title", "-year", "-runtime", "-rating", "-score"},
+		Cursor:   stringArg(p.Args, "cursor"),
+	}
+
+	if filters.Sort == "" {
+		filters.Sort = defaultSort
+	}
+
+	data.ValidateMovieQuery(v, q)
+	v.Check(q.Search != "" || !strings.Contains(filters.Sort, "score"), "sort", validators.CodeInvalid, "score can only be sorted on when searching with q")
+
+	if data.ValidateFilters(v, filters); !v.IsValid() {
+		return nil, graphqlValidationError(v)
+	}
+
+	movies, metadata, err := app.models.Movies.GetAllMovies(q, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &moviePage{movies: movies, metadata: metadata}, nil
+}
+
+func (app *application) resolveMovie(p graphql.ResolveParams) (any, error) {
+	err := app.graphqlRequirePermission(p.Context, "movies:read")
+	if err != nil {
+		return nil, err
+	}
+
+	id := movieID(p.Args["id"])
+	if id == 0 {
+		return nil, nil
+	}
+
+	movie, err := app.models.Movies.GetMovie(id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+// resolveMovieCredits loads the credits of every movie of a level with one query
+func (app *application) resolveMovieCredits(p graphql.BatchParams) ([]any, error) {
+	ids := make([]int64, len(p.Sources))
+	for i, source := range p.Sources {
+		ids[i] = source.(*data.Movies).ID
+	}
+
+	credits, err := app.models.Credits.GetForMovies(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(ids))
+	for i, id := range ids {
+		values[i] = credits[id]
+	}
+
+	return values, nil
+}
+
+// resolveMovieReviews loads the latest reviews of every movie of a level with one query
+func (app *application) resolveMovieReviews(p graphql.BatchParams) ([]any, error) {
+	limit := intArg(p.Args, "limit")
+
+	v := validators.New()
+	v.Check(limit > 0, "limit", validators.CodeOutOfRange, "must be greater than 0")
+	v.Check(limit <= 100, "limit", validators.CodeOutOfRange, "must be a maximum of 100")
+
+	if !v.IsValid() {
+		return nil, graphqlValidationError(v)
+	}
+
+	ids := make([]int64, len(p.Sources))
+	for i, source := range p.Sources {
+		ids[i] = source.(*data.Movies).ID
+	}
+
+	reviews, err := app.models.Reviews.GetForMovies(ids, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(ids))
+	for i, id := range ids {
+		values[i] = reviews[id]
+	}
+
+	return values, nil
+}
+
+func (app *application) resolveViewerPermissions(p graphql.ResolveParams) (any, error) {
+	permissions, err := app.graphqlPermissions(graphqlRequestFrom(p.Context))
+	if err != nil {
+		return nil, err
+	}
+
+	return []string(permissions), nil
+}
+
+func (app *application) resolveCreateMovie(p graphql.ResolveParams) (any, error) {
+	err := app.graphqlRequirePermission(p.Context, "movies:write")
+	if err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]any)
+
+	movie := &data.Movies{
+		Title:   stringArg(input, "title"),
+		Year:    int32(intArg(input, "year")),
+		Runtime: data.Runtime(intArg(input, "runtime")),
+		Genres:  stringsArg(input, "genres"),
+	}
+
+	v := validators.New()
+	if data.CheckValidators(v, movie); !v.IsValid() {
+		return nil, graphqlValidationError(v)
+	}
+
+	err = app.models.Movies.InsertMovie(movie, graphqlRequestFrom(p.Context).user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+// resolveUpdateMovie sets the fields the input holds. version stands for If-Match: the update only goes
+// through at the version the client has seen.
+func (app *application) resolveUpdateMovie(p graphql.ResolveParams) (any, error) {
+	err := app.graphqlRequirePermission(p.Context, "movies:write")
+	if err != nil {
+		return nil, err
+	}
+
+	movie, err := app.graphqlMovieAtVersion(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]any)
+
+	if title, ok := input["title"].(string); ok {
+		movie.Title = title
+	}
+
+	if year, ok := input["year"].(int); ok {
+		movie.Year = int32(year)
+	}
+
+	if runtime, ok := input["runtime"].(int); ok {
+		movie.Runtime = data.Runtime(runtime)
+	}
+
+	if _, ok := input["genres"].([]any); ok {
+		movie.Genres = stringsArg(input, "genres")
+	}
+
+	v := validators.New()
+	if data.CheckValidators(v, movie); !v.IsValid() {
+		return nil, graphqlValidationError(v)
+	}
+
+	movie, err = app.models.Movies.UpdateMovie(movie, graphqlRequestFrom(p.Context).user.ID)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			return nil, graphql.NewError("edit_conflict", "the movie was changed by someone else, read it again before updating it")
+		}
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+func (app *application) resolveDeleteMovie(p graphql.ResolveParams) (any, error) {
+	err := app.graphqlRequirePermission(p.Context, "movies:write")
+	if err != nil {
+		return nil, err
+	}
+
+	var version int32
+
+	if _, ok := p.Args["version"]; ok || app.config.conditional.requireIfMatch {
+		movie, err := app.graphqlMovieAtVersion(p.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		version = movie.Version
+	}
+
+	id := movieID(p.Args["id"])
+
+	err = app.models.Movies.DeleteMovie(id, version, graphqlRequestFrom(p.Context).user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			return nil, graphql.NewError("precondition_failed", "the movie was changed since the given version")
+		case errors.Is(err, data.ErrRecordNotFound):
+			return nil, graphql.NewError("not_found", data.ErrRecordNotFound.Error())
+		default:
+			return nil, err
+		}
+	}
+
+	return id, nil
+}
+
+// graphqlMovieAtVersion reads the movie a mutation is about, checking the version argument against it like
+// checkIfMatch checks the If-Match header
+func (app *application) graphqlMovieAtVersion(args map[string]any) (*data.Movies, error) {
+	notFound := graphql.NewError("not_found", data.ErrRecordNotFound.Error())
+
+	id := movieID(args["id"])
+	if id == 0 {
+		return nil, notFound
+	}
+
+	movie, err := app.models.Movies.GetMovie(id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+
+	version, ok := args["version"].(int)
+
+	switch {
+	case !ok && app.config.conditional.requireIfMatch:
+		return nil, graphql.NewError("precondition_required", "the version of the movie must be given")
+	case ok && int32(version) != movie.Version:
+		return nil, graphql.NewError("precondition_failed", "the movie was changed since the given version")
+	}
+
+	return movie, nil
+}
+
+func stringArg(args map[string]any, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+func intArg(args map[string]any, name string) int {
+	n, _ := args[name].(int)
+	return n
+}
+
+func stringsArg(args map[string]any, name string) []string {
+	list, _ := args[name].([]any)
+
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+
+	return values
+}
+
+// timeArg reads a time argument the way readTime reads one from the query string
+func timeArg(args map[string]any, name string, v *validators.Validators) time.Time {
+	s := stringArg(args, name)
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t
+	}
+
+	t, err = time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.AddErr(name, validators.CodeInvalid, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		return time.Time{}
+	}
+
+	return t
+}
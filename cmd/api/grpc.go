@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"movie-api/internal/data"
+	"movie-api/internal/rpc/moviev1"
+	"movie-api/internal/validators"
+	"net"
+	"strings"
+	"time"
+)
+
+// grpcPermissions are the permissions each method needs, the same the matching routes need. A method missing
+// from here is refused.
+var grpcPermissions = map[string]string{
+	moviev1.MovieService_ListMovies_FullMethodName:  "movies:read",
+	moviev1.MovieService_GetMovie_FullMethodName:    "movies:read",
+	moviev1.MovieService_CreateMovie_FullMethodName: "movies:write",
+	moviev1.MovieService_UpdateMovie_FullMethodName: "movies:write",
+	moviev1.MovieService_DeleteMovie_FullMethodName: "movies:write",
+}
+
+// grpcServer builds the gRPC server of the movie service, listening is left to the caller
+func (app *application) grpcServer() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(app.grpcRecoverPanic, app.grpcAuthenticate, app.grpcRequirePermission),
+	)
+
+	moviev1.RegisterMovieServiceServer(server, &movieServer{app: app})
+
+	return server
+}
+
+// serveGRPC starts the gRPC server on its own port, it returns once the server listens
+func (app *application) serveGRPC() (*grpc.Server, error) {
+	addr := fmt.Sprintf(":%d", app.config.grpc.port)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := app.grpcServer()
+
+	go func() {
+		app.logger.PrintInfo("starting grpc server", map[string]string{
+			"addr": addr,
+		})
+
+		err := server.Serve(listener)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"addr": addr,
+			})
+		}
+	}()
+
+	return server, nil
+}
+
+// stopGRPC lets the running calls finish, cutting them off when ctx is done first
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+func (app *application) grpcRecoverPanic(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			app.logger.PrintError(fmt.Errorf("%s", p), map[string]string{
+				"grpc_method": info.FullMethod,
+			})
+			err = status.Error(codes.Internal, serverErrorMessage)
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// grpcAuthenticate reads the token of the authorization metadata like authenticate reads the Authorization
+// header, calls without one are made by the anonymous user
+func (app *application) grpcAuthenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return handler(context.WithValue(ctx, userContextKey, data.AnonymousUser), req)
+	}
+
+	invalidToken := status.Error(codes.Unauthenticated, "invalid or missing authentication token")
+
+	headerParts := strings.Split(values[0], " ")
+	if len(values) != 1 || len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return nil, invalidToken
+	}
+
+	token := headerParts[1]
+
+	v := validators.New()
+
+	if data.ValidateTokenPlaintext(v, token); !v.IsValid() {
+		return nil, invalidToken
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return nil, invalidToken
+		default:
+			return nil, app.grpcError(ctx, err)
+		}
+	}
+
+	return handler(context.WithValue(ctx, userContextKey, user), req)
+}
+
+// grpcRequirePermission makes the checks of requirePermissionResponse before a method runs
+func (app *application) grpcRequirePermission(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	code, ok := grpcPermissions[info.FullMethod]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, notPermittedMessage)
+	}
+
+	user := grpcUser(ctx)
+
+	if user.IsAnonymous() {
+		return nil, status.Error(codes.Unauthenticated, authenticationRequiredMessage)
+	}
+
+	if !user.Activated {
+		return nil, status.Error(codes.PermissionDenied, inactiveAccountMessage)
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return nil, app.grpcError(ctx, err)
+	}
+
+	if !permissions.Include(code) {
+		return nil, status.Error(codes.PermissionDenied, notPermittedMessage)
+	}
+
+	return handler(ctx, req)
+}
+
+func grpcUser(ctx context.Context) *data.User {
+	user, ok := ctx.Value(userContextKey).(*data.User)
+	if !ok {
+		panic("missing user value in call context")
+	}
+
+	return user
+}
+
+// grpcError turns the errors of the models into statuses, logging the ones the caller can do nothing about
+func (app *application) grpcError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, data.ErrEditConflict):
+		return status.Error(codes.Aborted, "unable to update the record due to an edit conflict, please try again")
+	case errors.Is(err, data.ErrDuplicateEmail), errors.Is(err, data.ErrDuplicateReview), errors.Is(err, data.ErrDuplicateCredit):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, data.ErrInvalidRuntimeFormat):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	method, _ := grpc.Method(ctx)
+
+	app.logger.PrintError(err, map[string]string{
+		"grpc_method": method,
+	})
+
+	return status.Error(codes.Internal, serverErrorMessage)
+}
+
+// grpcValidationError reports invalid fields the way failedValidationResponse does, as the field violations
+// of a bad request
+func grpcValidationError(v *validators.Validators) error {
+	badRequest := &errdetails.BadRequest{}
+
+	for _, fe := range fieldErrors(v) {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fe.Field,
+			Description: fe.Message,
+		})
+	}
+
+	st, err := status.New(codes.InvalidArgument, "the request contains invalid values").WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "the request contains invalid values")
+	}
+
+	return st.Err()
+}
+
+// movieServer serves the movie service on top of the movie model, with the defaults and validation of the
+// movie routes
+type movieServer struct {
+	moviev1.UnimplementedMovieServiceServer
+	app *application
+}
+
+func (s *movieServer) ListMovies(ctx context.Context, req *moviev1.ListMoviesRequest) (*moviev1.ListMoviesResponse, error) {
+	v := validators.New()
+
+	q := data.MovieQuery{
+		Search:        req.GetQ(),
+		Title:         req.GetTitle(),
+		Genres:        req.GetGenres(),
+		GenresAny:     req.GetGenresAny(),
+		GenresExclude: req.GetGenresExclude(),
+		YearMin:       req.GetYearMin(),
+		YearMax:       req.GetYearMax(),
+		RuntimeMin:    data.Runtime(req.GetRuntimeMin()),
+		RuntimeMax:    data.Runtime(req.GetRuntimeMax()),
+		CreatedAfter:  timestampValue(req.GetCreatedAfter(), "created_after", v),
+		CreatedBefore: timestampValue(req.GetCreatedBefore(), "created_before", v),
+		Fields:        req.GetFields(),
+	}
+
+	// searches come best match first unless asked otherwise, like on GET /v1/movies
+	defaultSort := "id"
+	if q.Search != "" {
+		defaultSort = "-score"
+	}
+
+	filters := data.Filters{
+		Page:      int(req.GetPage()),
+		PageSize:  int(req.GetPageSize()),
+		Sort:      req.GetSort(),
+		SortList:  []string{"id", "title", "year", "runtime", "rating", "score", "-id", "-title", "-year", "-runtime", "-rating", "-score"},
+		Cursor:    req.GetCursor(),
+		SkipTotal: req.GetSkipTotal(),
+	}
+
+	if filters.Page == 0 {
+		filters.Page = 1
+	}
+
+	if filters.PageSize == 0 {
+		filters.PageSize = 20
+	}
+
+	if filters.Sort == "" {
+		filters.Sort = defaultSort
+	}
+
+	data.ValidateMovieQuery(v, q)
+	data.ValidateMovieFields(v, q.Fields)
+	v.Check(q.Search != "" || !strings.Contains(filters.Sort, "score"), "sort", validators.CodeInvalid, "score can only be sorted on when searching with q")
+
+	if data.ValidateFilters(v, filters); !v.IsValid() {
+		return nil, grpcValidationError(v)
+	}
+
+	movies, metadata, err := s.app.models.Movies.GetAllMovies(q, filters)
+	if err != nil {
+		return nil, s.app.grpcError(ctx, err)
+	}
+
+	resp := &moviev1.ListMoviesResponse{
+		Movies: make([]*moviev1.Movie, len(movies)),
+		Metadata: &moviev1.Metadata{
+			CurrentPage:  int32(metadata.CurrentPage),
+			PageSize:     int32(metadata.PageSize),
+			FirstPage:    int32(metadata.FirstPage),
+			LastPage:     int32(metadata.LastPage),
+			TotalRecords: int32(metadata.TotalRecords),
+			NextCursor:   metadata.NextCursor,
+			PrevCursor:   metadata.PrevCursor,
+		},
+	}
+
+	for i, movie := range movies {
+		resp.Movies[i] = movieMessage(movie)
+	}
+
+	return resp, nil
+}
+
+func (s *movieServer) GetMovie(ctx context.Context, req *moviev1.GetMovieRequest) (*moviev1.Movie, error) {
+	v := validators.New()
+
+	if data.ValidateMovieFields(v, req.GetFields()); !v.IsValid() {
+		return nil, grpcValidationError(v)
+	}
+
+	if req.GetId() < 1 {
+		return nil, s.app.grpcError(ctx, data.ErrRecordNotFound)
+	}
+
+	movie, err := s.app.models.Movies.GetMovie(req.GetId(), req.GetFields()...)
+	if err != nil {
+		return nil, s.app.grpcError(ctx, err)
+	}
+
+	return movieMessage(movie), nil
+}
+
+func (s *movieServer) CreateMovie(ctx context.Context, req *moviev1.CreateMovieRequest) (*moviev1.Movie, error) {
+	movie := &data.Movies{
+		Title:   req.GetTitle(),
+		Year:    req.GetYear(),
+		Runtime: data.Runtime(req.GetRuntime()),
+		Genres:  req.GetGenres(),
+	}
+
+	v := validators.New()
+
+	if data.CheckValidators(v, movie); !v.IsValid() {
+		return nil, grpcValidationError(v)
+	}
+
+	err := s.app.models.Movies.InsertMovie(movie, grpcUser(ctx).ID)
+	if err != nil {
+		return nil, s.app.grpcError(ctx, err)
+	}
+
+	return movieMessage(movie), nil
+}
+
+// UpdateMovie sets the fields the request holds. A version other than 0 stands for If-Match: the update only
+// goes through at the version the caller has seen.
+func (s *movieServer) UpdateMovie(ctx context.Context, req *moviev1.UpdateMovieRequest) (*moviev1.Movie, error) {
+	movie, err := s.movieAtVersion(ctx, req.GetId(), req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		movie.Title = req.GetTitle()
+	}
+
+	if req.Year != nil {
+		movie.Year = req.GetYear()
+	}
+
+	if req.Runtime != nil {
+		movie.Runtime = data.Runtime(req.GetRuntime())
+	}
+
+	if req.Genres != nil {
+		movie.Genres = req.GetGenres().GetValues()
+	}
+
+	v := validators.New()
+
+	if data.CheckValidators(v, movie); !v.IsValid() {
+		return nil, grpcValidationError(v)
+	}
+
+	movie, err = s.app.models.Movies.UpdateMovie(movie, grpcUser(ctx).ID)
+	if err != nil {
+		return nil, s.app.grpcError(ctx, err)
+	}
+
+	return movieMessage(movie), nil
+}
+
+func (s *movieServer) DeleteMovie(ctx context.Context, req *moviev1.DeleteMovieRequest) (*moviev1.DeleteMovieResponse, error) {
+	var version int32
+
+	if req.GetVersion() != 0 || s.app.config.conditional.requireIfMatch {
+		movie, err := s.movieAtVersion(ctx, req.GetId(), req.GetVersion())
+		if err != nil {
+			return nil, err
+		}
+
+		version = movie.Version
+	}
+
+	err := s.app.models.Movies.DeleteMovie(req.GetId(), version, grpcUser(ctx).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			return nil, status.Error(codes.FailedPrecondition, "the movie was changed since the given version")
+		default:
+			return nil, s.app.grpcError(ctx, err)
+		}
+	}
+
+	return &moviev1.DeleteMovieResponse{}, nil
+}
+
+// movieAtVersion reads the movie a call changes, checking the version of the request against it like
+// checkIfMatch checks the If-Match header
+func (s *movieServer) movieAtVersion(ctx context.Context, id int64, version int32) (*data.Movies, error) {
+	if id < 1 {
+		return nil, s.app.grpcError(ctx, data.ErrRecordNotFound)
+	}
+
+	movie, err := s.app.models.Movies.GetMovie(id)
+	if err != nil {
+		return nil, s.app.grpcError(ctx, err)
+	}
+
+	switch {
+	case version == 0 && s.app.config.conditional.requireIfMatch:
+		return nil, status.Error(codes.FailedPrecondition, "the version of the movie must be given")
+	case version != 0 && version != movie.Version:
+		return nil, status.Error(codes.FailedPrecondition, "the movie was changed since the given version")
+	}
+
+	return movie, nil
+}
+
+func movieMessage(movie *data.Movies) *moviev1.Movie {
+	return &moviev1.Movie{
+		Id:            movie.ID,
+		Title:         movie.Title,
+		Year:          movie.Year,
+		Runtime:       int32(movie.Runtime),
+		Genres:        movie.Genres,
+		AverageRating: movie.AverageRating,
+		RatingCount:   movie.RatingCount,
+		Version:       movie.Version,
+	}
+}
+
+// timestampValue reads an optional timestamp of a request, unset ones are the zero time
+func timestampValue(ts *timestamppb.Timestamp, field string, v *validators.Validators) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+
+	if err := ts.CheckValid(); err != nil {
+		v.AddErr(field, validators.CodeInvalid, "must be a valid timestamp")
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"movie-api/internal/data"
+	"movie-api/internal/rpc/moviev1"
+	"net"
+	"testing"
+)
+
+// the tokens of the users of the gRPC tests, testToken belongs to a user who can read and write movies
+const (
+	readerToken   = "READERREADERREADERREADER12"
+	inactiveToken = "INACTIVEINACTIVEINACTIVE12"
+	unknownToken  = "UNKNOWNUNKNOWNUNKNOWNUNKNO"
+)
+
+// panickingMovies is a movie model whose reads panic
+type panickingMovies struct {
+	data.MovieMockModel
+}
+
+func (panickingMovies) GetMovie(id int64, fields ...string) (*data.Movies, error) {
+	panic("the movie model broke")
+}
+
+// newGRPCClient serves the gRPC service of the models over an in-memory connection
+func newGRPCClient(t *testing.T, models data.Models) moviev1.MovieServiceClient {
+	t.Helper()
+
+	writer := &data.User{ID: 1, Activated: true}
+	reader := &data.User{ID: 2, Activated: true}
+	inactive := &data.User{ID: 3}
+
+	models.Users = data.UserMockModel{Tokens: map[string]*data.User{testToken: writer, readerToken: reader, inactiveToken: inactive}}
+	models.Permissions = data.PermissionMockModel{Permissions: map[int64]data.Permissions{
+		writer.ID:   {"movies:read", "movies:write"},
+		reader.ID:   {"movies:read"},
+		inactive.ID: {"movies:read", "movies:write"},
+	}}
+
+	listener := bufconn.Listen(1 << 20)
+
+	server := newTestApplication(models).grpcServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return moviev1.NewMovieServiceClient(conn)
+}
+
+func withAuthorization(authorization string) context.Context {
+	if authorization == "" {
+		return context.Background()
+	}
+
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", authorization)
+}
+
+func TestGRPCInterceptors(t *testing.T) {
+	c := newGRPCClient(t, data.NewMovieMockModel(&data.Movies{ID: 1, Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}, Version: 1}))
+
+	get := func(ctx context.Context) error {
+		_, err := c.GetMovie(ctx, &moviev1.GetMovieRequest{Id: 1})
+		return err
+	}
+	create := func(ctx context.Context) error {
+		_, err := c.CreateMovie(ctx, &moviev1.CreateMovieRequest{Title: "Ran", Year: 1999, Runtime: 162, Genres: []string{"drama"}})
+		return err
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		call          func(ctx context.Context) error
+		code          codes.Code
+	}{
+		{"anonymous", "", get, codes.Unauthenticated},
+		{"not a bearer token", "Basic " + testToken, get, codes.Unauthenticated},
+		{"malformed token", "Bearer short", get, codes.Unauthenticated},
+		{"unknown token", "Bearer " + unknownToken, get, codes.Unauthenticated},
+		{"inactive user", "Bearer " + inactiveToken, get, codes.PermissionDenied},
+		{"reader reads", "Bearer " + readerToken, get, codes.OK},
+		{"reader writes", "Bearer " + readerToken, create, codes.PermissionDenied},
+		{"writer reads", "Bearer " + testToken, get, codes.OK},
+		{"writer writes", "Bearer " + testToken, create, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call(withAuthorization(tt.authorization))); got != tt.code {
+				t.Errorf("got %s, want %s", got, tt.code)
+			}
+		})
+	}
+
+	md := metadata.Pairs("authorization", "Bearer "+testToken, "authorization", "Bearer "+testToken)
+	if got := status.Code(get(metadata.NewOutgoingContext(context.Background(), md))); got != codes.Unauthenticated {
+		t.Errorf("two tokens: got %s, want %s", got, codes.Unauthenticated)
+	}
+}
+
+func TestGRPCRequirePermissionUnknownMethod(t *testing.T) {
+	app := newTestApplication(data.NewMovieMockModel())
+	ctx := context.WithValue(context.Background(), userContextKey, &data.User{ID: 1, Activated: true})
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	}
+
+	_, err := app.grpcRequirePermission(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/movie.v1.MovieService/Undocumented"}, handler)
+	if status.Code(err) != codes.PermissionDenied || called {
+		t.Errorf("got %v and a call %t, want the method refused", err, called)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	ctx := withAuthorization("Bearer " + testToken)
+	c := newGRPCClient(t, data.NewMovieMockModel(&data.Movies{ID: 1, Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}, Version: 2}))
+
+	_, err := c.GetMovie(ctx, &moviev1.GetMovieRequest{Id: 99})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("missing movie: got %v, want %s", err, codes.NotFound)
+	}
+
+	title := "Heat (1995)"
+
+	_, err = c.UpdateMovie(ctx, &moviev1.UpdateMovieRequest{Id: 1, Title: &title, Version: 1})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("stale version: got %v, want %s", err, codes.FailedPrecondition)
+	}
+
+	movie, err := c.UpdateMovie(ctx, &moviev1.UpdateMovieRequest{Id: 1, Title: &title, Version: 2})
+	if err != nil || movie.GetTitle() != title || movie.GetVersion() != 3 {
+		t.Errorf("update: got %v, %v", movie, err)
+	}
+
+	_, err = c.CreateMovie(ctx, &moviev1.CreateMovieRequest{Year: 1999, Runtime: 162, Genres: []string{"drama", "drama"}})
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("invalid movie: got %v, want %s", err, codes.InvalidArgument)
+	}
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+
+	if fmt.Sprint(fields) != "[genres title]" {
+		t.Errorf("invalid movie: got field violations %v, want genres and title", fields)
+	}
+
+	models := data.NewMovieMockModel()
+	models.Movies = panickingMovies{}
+
+	_, err = newGRPCClient(t, models).GetMovie(ctx, &moviev1.GetMovieRequest{Id: 1})
+	if st := status.Convert(err); st.Code() != codes.Internal || st.Message() != serverErrorMessage {
+		t.Errorf("panic: got %v, want %s", err, codes.Internal)
+	}
+}
+
+func TestGRPCErrorCodes(t *testing.T) {
+	app := newTestApplication(data.NewMovieMockModel())
+
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{data.ErrRecordNotFound, codes.NotFound},
+		{fmt.Errorf("reading the movie: %w", data.ErrRecordNotFound), codes.NotFound},
+		{data.ErrEditConflict, codes.Aborted},
+		{data.ErrDuplicateEmail, codes.AlreadyExists},
+		{data.ErrDuplicateReview, codes.AlreadyExists},
+		{data.ErrDuplicateCredit, codes.AlreadyExists},
+		{data.ErrInvalidRuntimeFormat, codes.InvalidArgument},
+		{errors.New("connection refused"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		err := app.grpcError(context.Background(), tt.err)
+
+		st := status.Convert(err)
+		if st.Code() != tt.code {
+			t.Errorf("%v: got %s, want %s", tt.err, st.Code(), tt.code)
+		}
+
+		if tt.code == codes.Internal && st.Message() != serverErrorMessage {
+			t.Errorf("%v: got the message %q, want it hidden", tt.err, st.Message())
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
+	"mime"
 	"movie-api/internal/data"
 	"movie-api/internal/validators"
 	"net/http"
+	"strings"
 )
 
 func (app *application) healthcheck(w http.ResponseWriter, r *http.Request) {
@@ -31,7 +34,7 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		app.readBodyErrorResponse(w, r, err)
 		return
 	}
 
@@ -43,12 +46,15 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	v := validators.New()
+
+	movie.SetRuntimeFormat(app.readRuntimeFormat(r.URL.Query(), v))
+
 	if data.CheckValidators(v, movie); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	err = app.models.Movies.InsertMovie(movie)
+	err = app.models.Movies.InsertMovie(movie, app.contextGetUser(r).ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -63,10 +69,7 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 
 func (app *application) getAllMovies(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title string `json:"title"`
-		Year  int32  `json:"year"`
-		//Runtime data.Runtime `json:"runtime"`
-		Genres []string `json:"genres"`
+		data.MovieQuery
 		data.Filters
 	}
 
@@ -74,27 +77,69 @@ func (app *application) getAllMovies(w http.ResponseWriter, r *http.Request) {
 
 	qs := r.URL.Query()
 
-	input.Title = app.readString(qs, "title", "")
-	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.MovieQuery = app.readMovieQuery(qs, v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
-	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortList = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	// searches come best match first unless asked otherwise
+	defaultSort := "id"
+	if input.MovieQuery.Search != "" {
+		defaultSort = "-score"
+	}
+
+	input.Filters.Sort = app.readString(qs, "sort", defaultSort)
+	input.Filters.SortList = []string{"id", "title", "year", "runtime", "rating", "score", "-id", "-title", "-year", "-runtime", "-rating", "-score"}
+
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	input.Filters.SkipTotal = !app.readBool(qs, "include_total", true, v)
+
+	facets := app.readCSV(qs, "facets", []string{})
+	for _, facet := range facets {
+		v.Check(validators.PermittedValues(facet, data.FacetGenres, data.FacetYear), "facets", validators.CodeUnknownValue, "unknown facet "+facet)
+	}
+	v.Check(validators.Unique(facets), "facets", validators.CodeDuplicate, "facets values cannot be duplicated")
+
+	input.MovieQuery.Fields = app.readCSV(qs, "fields", []string{})
+	include := app.readMovieIncludes(qs, v)
+	runtimeFormat := app.readRuntimeFormat(qs, v)
+
+	data.ValidateMovieQuery(v, input.MovieQuery)
+	data.ValidateMovieFields(v, input.MovieQuery.Fields)
+	v.Check(input.MovieQuery.Search != "" || !strings.Contains(input.Filters.Sort, "score"), "sort", validators.CodeInvalid, "score can only be sorted on when searching with q")
 
 	if data.ValidateFilters(v, input.Filters); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	movies, metadata, err := app.models.Movies.GetAllMovies(input.Title, input.Genres, input.Filters)
+	movies, metadata, err := app.models.Movies.GetAllMovies(input.MovieQuery, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, 200, envelope{"movies": movies, "metadata": metadata}, nil)
+	err = app.includeMovies(movies, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, movie := range movies {
+		movie.SetRuntimeFormat(runtimeFormat)
+	}
+
+	env := envelope{"movies": movies, "metadata": metadata}
+
+	if len(facets) != 0 {
+		env["facets"], err = app.models.Movies.GetMovieFacets(input.MovieQuery, facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, 200, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -104,26 +149,58 @@ func (app *application) getAllMovies(w http.ResponseWriter, r *http.Request) {
 func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.getId(r)
 	if err != nil {
+		app.notFoundResponse(w, r)
 		return
 	}
 
-	reqMovie, err := app.models.Movies.GetMovie(id)
+	v := validators.New()
+
+	qs := r.URL.Query()
+
+	fields := app.readCSV(qs, "fields", []string{})
+	include := app.readMovieIncludes(qs, v)
+	runtimeFormat := app.readRuntimeFormat(qs, v)
+
+	if data.ValidateMovieFields(v, fields); !v.IsValid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	movie, err := app.models.Movies.GetMovie(id, fields...)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movie.SetRuntimeFormat(runtimeFormat)
+
+	err = app.includeMovies([]*data.Movies{movie}, include)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	movie := data.Movies{
-		ID:        reqMovie.ID,
-		CreatedAt: reqMovie.CreatedAt,
-		Title:     reqMovie.Title,
-		Year:      reqMovie.Year,
-		Runtime:   reqMovie.Runtime,
-		Genres:    reqMovie.Genres,
-		Version:   reqMovie.Version,
+	plain := len(fields) == 0 && len(include) == 0 && runtimeFormat == data.RuntimeMinutes
+
+	etag, err := movieRepresentationETag(w, movie, plain)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if app.notModified(w, r, etag) {
+		return
 	}
 
-	err = app.writeJSON(w, 200, envelope{"movie": movie}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+
+	err = app.writeJSON(w, 200, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -134,60 +211,146 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	movieId, err := app.getId(r)
 	if err != nil {
+		app.notFoundResponse(w, r)
 		return
 	}
 
+	// a plain body sets the fields it holds, the patch formats edit the current movie document
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	patch := validators.PermittedValues(mediaType, mergePatchType, jsonPatchType)
+
 	movie, err := app.models.Movies.GetMovie(movieId)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
-	var input struct {
-		Title   *string       `json:"title"`
-		Year    *int32        `json:"year"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  []string      `json:"genres"`
-	}
-
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+	if !app.checkIfMatch(w, r, movieETag(movie), movieVersionETag(movie)) {
 		return
 	}
 
-	if input.Title != nil {
-		movie.Title = *input.Title
-	}
+	v := validators.New()
 
-	if input.Year != nil {
-		movie.Year = *input.Year
+	movie.SetRuntimeFormat(app.readRuntimeFormat(r.URL.Query(), v))
+
+	if patch {
+		err = app.patchMovie(w, r, mediaType, movie, v)
+		if err != nil {
+			app.readBodyErrorResponse(w, r, err)
+			return
+		}
+
+		if !v.IsValid() {
+			app.failedValidationResponse(w, r, v)
+			return
+		}
+	} else {
+		var input struct {
+			Title   *string       `json:"title"`
+			Year    *int32        `json:"year"`
+			Runtime *data.Runtime `json:"runtime"`
+			Genres  []string      `json:"genres"`
+		}
+
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.readBodyErrorResponse(w, r, err)
+			return
+		}
+
+		if input.Title != nil {
+			movie.Title = *input.Title
+		}
+
+		if input.Year != nil {
+			movie.Year = *input.Year
+		}
+
+		if input.Runtime != nil {
+			movie.Runtime = *input.Runtime
+		}
+
+		if input.Genres != nil {
+			movie.Genres = input.Genres
+		}
 	}
 
-	if input.Runtime != nil {
-		movie.Runtime = *input.Runtime
+	if data.CheckValidators(v, movie); !v.IsValid() {
+		app.failedValidationResponse(w, r, v)
+		return
 	}
 
-	if input.Genres != nil {
-		movie.Genres = input.Genres
+	newMovie, err := app.models.Movies.UpdateMovie(movie, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
 	}
 
-	v := validators.New()
-	if data.CheckValidators(v, movie); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+	headers := make(http.Header)
+	headers.Set("ETag", movieETag(newMovie))
+
+	err = app.writeJSON(w, 200, envelope{"movie": newMovie}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	newMovie, err := app.models.Movies.UpdateMovie(movie)
+}
+
+func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.getId(r)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.writeJSON(w, 200, envelope{"movie": newMovie}, nil)
+	// a conditional delete only goes through at the version the client has seen
+	var version int32
+
+	if r.Header.Get("If-Match") != "" || app.config.conditional.requireIfMatch {
+		movie, err := app.models.Movies.GetMovie(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !app.checkIfMatch(w, r, movieETag(movie), movieVersionETag(movie)) {
+			return
+		}
+
+		version = movie.Version
+	}
+
+	err = app.models.Movies.DeleteMovie(id, version, app.contextGetUser(r).ID)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.preconditionFailedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
@@ -1,64 +1,121 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"io"
+	"math"
+	"movie-api/internal/data"
 	"movie-api/internal/validators"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type envelope map[string]any
 
-func (app application) getId(r *http.Request) (int64, error) {
+func (app *application) getId(r *http.Request) (int64, error) {
+	return app.getParam(r, "id")
+}
+
+func (app *application) getVersion(r *http.Request) (int32, error) {
+	version, err := app.getParam(r, "version")
+	if err != nil {
+		return 0, err
+	}
+
+	if version > math.MaxInt32 {
+		return 0, errors.New("invalid version parameter")
+	}
+
+	return int32(version), nil
+}
+
+// getParam reads a positive integer route parameter
+func (app *application) getParam(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
-	param := params.ByName("id")
+	param := params.ByName(name)
 
-	id, err := strconv.ParseInt(param, 10, 64)
+	value, err := strconv.ParseInt(param, 10, 64)
 	if err != nil {
 		return 0, err
 	}
 
-	if id < 1 {
-		return 0, errors.New("invalid id parameter")
+	if value < 1 {
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 
-	return id, nil
+	return value, nil
+}
+
+// writeJSON writes the envelope in the representation the request accepts, JSON unless it asks for another one
+func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
+	return app.writeResponse(w, status, data, headers, false)
 }
 
-func (app application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
-	json, err := json.Marshal(data)
+// writeResponse negotiates the representation of data like writeJSON, problem details are written with the
+// problem media types
+func (app *application) writeResponse(w http.ResponseWriter, status int, data any, headers http.Header, problem bool) error {
+	js, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
+	contentType, body, err := encodeResponse(acceptHeader(w), status, js)
+	if err != nil {
+		if !errors.Is(err, errNotAcceptable) {
+			return err
+		}
+
+		status, contentType, body = http.StatusNotAcceptable, "application/json", app.notAcceptableBody()
+		problem = app.config.errorFormat != errorFormatLegacy
+	}
+
+	if problem {
+		contentType = problemMediaType(contentType)
+	}
+
 	for key, value := range headers {
 		w.Header()[key] = value
 	}
 
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+
 	w.WriteHeader(status)
-	w.Write(json)
+	w.Write(body)
 
 	return nil
 }
 
-func (app application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	maxBytes := 1_000_000
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
-	d := json.NewDecoder(r.Body)
-	d.DisallowUnknownFields()
 
 	var syntaxError *json.SyntaxError
 	var invalidUnmarshalError *json.InvalidUnmarshalError
 	var unmarshalTypeError *json.UnmarshalTypeError
 	var maxBytesError *http.MaxBytesError
 
-	err := d.Decode(dst)
+	body, err := readBody(r)
+	if err != nil {
+		switch {
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("the JSON object is over the appropiated bytes limit (%d)", maxBytesError.Limit)
+		default:
+			return err
+		}
+	}
+
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.DisallowUnknownFields()
+
+	err = d.Decode(dst)
 	if err != nil {
 		switch {
 		case errors.As(err, &syntaxError):
@@ -75,8 +132,6 @@ func (app application) readJSON(w http.ResponseWriter, r *http.Request, dst any)
 		case strings.HasPrefix(err.Error(), "json: unknown field"):
 			field := strings.TrimPrefix(err.Error(), "json: unknown field")
 			return fmt.Errorf("body contains an unknown field %s", field)
-		case errors.As(err, &maxBytesError):
-			return fmt.Errorf("the JSON object is over the appropiated bytes limit (%d)", maxBytesError.Limit)
 		case errors.As(err, &invalidUnmarshalError):
 			panic(err)
 		default:
@@ -121,13 +176,76 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 
 	i, err := strconv.Atoi(s)
 	if err != nil {
-		v.AddErr(key, "must be an integer value")
+		v.AddErr(key, validators.CodeInvalid, "must be an integer value")
 		return defaultValue
 	}
 
 	return i
 }
 
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validators.Validators) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddErr(key, validators.CodeInvalid, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
+// readTime reads an RFC 3339 timestamp or a plain 2006-01-02 date, taken as midnight UTC
+func (app *application) readTime(qs url.Values, key string, v *validators.Validators) time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t
+	}
+
+	t, err = time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.AddErr(key, validators.CodeInvalid, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		return time.Time{}
+	}
+
+	return t
+}
+
+// readRuntimeFormat reads the format the runtimes of movie responses are written in
+func (app *application) readRuntimeFormat(qs url.Values, v *validators.Validators) string {
+	format := app.readString(qs, "runtime_format", data.RuntimeMinutes)
+	v.Check(validators.PermittedValues(format, data.RuntimeMinutes, data.RuntimeISO8601, data.RuntimeHuman), "runtime_format", validators.CodeInvalid, "must be minutes, iso8601 or human")
+
+	return format
+}
+
+// readMovieQuery reads the filters shared by the endpoints listing movies
+func (app *application) readMovieQuery(qs url.Values, v *validators.Validators) data.MovieQuery {
+	return data.MovieQuery{
+		Search:        app.readString(qs, "q", ""),
+		Title:         app.readString(qs, "title", ""),
+		Genres:        app.readCSV(qs, "genres", []string{}),
+		GenresAny:     app.readCSV(qs, "genres_any", []string{}),
+		GenresExclude: app.readCSV(qs, "genres_exclude", []string{}),
+		YearMin:       int32(app.readInt(qs, "year_min", 0, v)),
+		YearMax:       int32(app.readInt(qs, "year_max", 0, v)),
+		RuntimeMin:    data.Runtime(app.readInt(qs, "runtime_min", 0, v)),
+		RuntimeMax:    data.Runtime(app.readInt(qs, "runtime_max", 0, v)),
+		CreatedAfter:  app.readTime(qs, "created_after", v),
+		CreatedBefore: app.readTime(qs, "created_before", v),
+	}
+}
+
 // background helps a goroutine to recover from panic
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"movie-api/internal/data"
+	"net/http"
+	"time"
+)
+
+// idempotencyRecorder passes a response through while keeping a copy of it to store
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// idempotent makes a POST safe to retry when it carries an Idempotency-Key header. The first request with a key
+// runs and its response is stored, retries with the same key and request get that response back instead of
+// running again. Keys belong to the authenticated user. Anonymous keys are scoped by the request as well, so
+// only a retry sending the very same request, password and all, gets the stored response back; the key is
+// never shared with another one. Responses with a server error aren't stored, so the request can be retried
+// for real.
+func (app *application) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > 255 {
+			app.errorResponse(w, r, http.StatusBadRequest, "invalid_idempotency_key", "the Idempotency-Key header must not be more than 255 bytes long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1_000_000))
+		if err != nil {
+			app.readBodyErrorResponse(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// the Accept header picks the representation of the response, a retry asking for another one differs
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n" + r.Header.Get("Accept") + "\n"))
+		hash.Write(body)
+
+		user := app.contextGetUser(r)
+		userID := user.ID
+
+		if user.IsAnonymous() {
+			key += " " + hex.EncodeToString(hash.Sum(nil))
+		}
+
+		stored, err := app.models.Idempotency.Claim(userID, key, hash.Sum(nil), app.config.idempotency.lease)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrIdempotencyKeyReused):
+				app.idempotencyKeyReusedResponse(w, r)
+			case errors.Is(err, data.ErrIdempotencyKeyInFlight):
+				app.idempotencyKeyInFlightResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if stored != nil {
+			for name, values := range stored.Headers {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w}
+
+		// a panic or a server error gives the key back, nothing was stored for it
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+
+			err := app.models.Idempotency.Release(userID, key)
+			if err != nil {
+				app.logError(r, err)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+			return
+		}
+
+		err = app.models.Idempotency.Complete(userID, key, &data.StoredResponse{
+			Status:  rec.status,
+			Headers: w.Header().Clone(),
+			Body:    rec.body.Bytes(),
+		}, app.config.idempotency.ttl)
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+
+		completed = true
+	}
+}
+
+// runIdempotencyCleanup deletes the expired idempotency keys every hour until stop is closed, counting in app.wg
+// like runDigests
+func (app *application) runIdempotencyCleanup(stop <-chan struct{}) {
+	defer app.wg.Done()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := app.models.Idempotency.DeleteExpired()
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}
+	}
+}
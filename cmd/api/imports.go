@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"movie-api/internal/data"
+	"movie-api/internal/validators"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxImportBytes caps the size of an import upload
+	maxImportBytes = 32 << 20
+	// maxImportRows caps the amount of rows of a single import
+	maxImportRows = 50_000
+)
+
+// importRow is a parsed row of an import file, movie is nil when the row couldn't be decoded
+type importRow struct {
+	row    int
+	movie  *data.Movies
+	errors map[string]string
+}
+
+// importMoviesHandler validates a CSV or NDJSON upload row by row and queues the valid rows to be copied in the
+// background. CSV files need a title,year,runtime,genres header and separate the genres of a row with "|".
+func (app *application) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validators.New()
+
+	qs := r.URL.Query()
+
+	mode := app.readString(qs, "mode", data.ImportInsert)
+	dryRun := app.readBool(qs, "dry_run", false, v)
+
+	v.Check(validators.PermittedValues(mode, data.ImportInsert, data.ImportUpsert), "mode", validators.CodeInvalid, "must be insert or upsert")
+
+	if !v.IsValid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var rows []*importRow
+
+	switch mediaType {
+	case "text/csv":
+		rows, err = app.readImportCSV(r.Body)
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		rows, err = app.readImportNDJSON(r.Body)
+	default:
+		app.unsupportedMediaTypeResponse(w, r)
+		return
+	}
+
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			v.AddErr("file", validators.CodeInvalid, fmt.Sprintf("must not be larger than %d bytes", maxBytesError.Limit))
+			app.failedValidationResponse(w, r, v)
+		default:
+			v.AddErr("file", validators.CodeInvalid, err.Error())
+			app.failedValidationResponse(w, r, v)
+		}
+		return
+	}
+
+	job := &data.ImportJob{
+		UserID:    app.contextGetUser(r).ID,
+		Mode:      mode,
+		DryRun:    dryRun,
+		Status:    data.ImportPending,
+		TotalRows: len(rows),
+		RowErrors: []data.ImportRowError{},
+	}
+
+	movies := app.validateImportRows(rows, mode)
+
+	for _, row := range rows {
+		if len(row.errors) != 0 {
+			job.RowErrors = append(job.RowErrors, data.ImportRowError{Row: row.row, Errors: row.errors})
+		}
+	}
+
+	job.ValidRows = len(movies)
+
+	if dryRun || len(movies) == 0 {
+		finishedAt := time.Now()
+		job.Status = data.ImportCompleted
+		job.FinishedAt = &finishedAt
+	}
+
+	err = app.models.Imports.Insert(job)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/imports/%d", job.ID))
+
+	if job.Status == data.ImportCompleted {
+		err = app.writeJSON(w, http.StatusOK, envelope{"import": job}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.background(func() {
+		app.runImport(job, movies)
+	})
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"import": job}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showImportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.getId(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.Imports.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if job.UserID != app.contextGetUser(r).ID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"import": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// runImport copies the valid rows of a job and records how it ended
+func (app *application) runImport(job *data.ImportJob, movies []*data.Movies) {
+	job.Status = data.ImportRunning
+
+	err := app.models.Imports.UpdateProgress(job)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+	}
+
+	err = app.models.Imports.Run(job, movies)
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = data.ImportCompleted
+
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"import_id": strconv.FormatInt(job.ID, 10),
+		})
+		job.Status = data.ImportFailed
+		job.Failure = err.Error()
+	}
+
+	err = app.models.Imports.UpdateProgress(job)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+	}
+}
+
+// validateImportRows checks every decoded row with CheckValidators and returns the movies of the valid ones.
+// In upsert mode a title and year pair can only appear once, otherwise the update would be ambiguous.
+func (app *application) validateImportRows(rows []*importRow, mode string) []*data.Movies {
+	var movies []*data.Movies
+	seen := make(map[string]int)
+
+	for _, row := range rows {
+		if row.movie == nil {
+			continue
+		}
+
+		v := validators.New()
+		data.CheckValidators(v, row.movie)
+
+		if mode == data.ImportUpsert {
+			key := fmt.Sprintf("%s\x00%d", row.movie.Title, row.movie.Year)
+			if first, found := seen[key]; found {
+				v.AddErr("title", validators.CodeDuplicate, fmt.Sprintf("title and year already appear on row %d", first))
+			} else {
+				seen[key] = row.row
+			}
+		}
+
+		if !v.IsValid() {
+			row.errors = v.Errors
+			continue
+		}
+
+		movies = append(movies, row.movie)
+	}
+
+	return movies
+}
+
+func (app *application) readImportCSV(body io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("must not be empty")
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"title", "year", "runtime", "genres"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("header is missing the %s column", name)
+		}
+	}
+
+	var rows []*importRow
+
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("must not contain more than %d rows", maxImportRows)
+		}
+
+		row := &importRow{row: n, errors: map[string]string{}}
+		rows = append(rows, row)
+
+		if err != nil {
+			var parseError *csv.ParseError
+			if errors.As(err, &parseError) {
+				row.errors["row"] = parseError.Err.Error()
+				continue
+			}
+			return nil, err
+		}
+
+		if len(record) != len(header) {
+			row.errors["row"] = fmt.Sprintf("must have %d fields", len(header))
+			continue
+		}
+
+		movie := &data.Movies{Title: record[columns["title"]]}
+
+		year, err := strconv.ParseInt(record[columns["year"]], 10, 32)
+		if err != nil {
+			row.errors["year"] = "must be an integer value"
+		}
+		movie.Year = int32(year)
+
+		movie.Runtime, err = importRuntime(record[columns["runtime"]])
+		if err != nil {
+			row.errors["runtime"] = `must be a number of minutes or a runtime like "102 mins", "1h 42m" or "PT1H42M"`
+		}
+
+		for _, genre := range strings.Split(record[columns["genres"]], "|") {
+			if genre = strings.TrimSpace(genre); genre != "" {
+				movie.Genres = append(movie.Genres, genre)
+			}
+		}
+
+		if len(row.errors) == 0 {
+			row.movie = movie
+		}
+	}
+
+	return rows, nil
+}
+
+// importRuntime reads the runtime cell of a CSV row. Exports write it as a bare number of minutes, which
+// the runtime formats of the JSON documents don't take.
+func importRuntime(cell string) (data.Runtime, error) {
+	if minutes, err := strconv.ParseInt(cell, 10, 32); err == nil {
+		if minutes < 0 {
+			return 0, data.ErrInvalidRuntimeFormat
+		}
+
+		return data.Runtime(minutes), nil
+	}
+
+	return data.ParseRuntime(cell)
+}
+
+func (app *application) readImportNDJSON(body io.Reader) ([]*importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1_000_000)
+
+	var rows []*importRow
+
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("must not contain more than %d rows", maxImportRows)
+		}
+
+		var input struct {
+			Title   string       `json:"title"`
+			Year    int32        `json:"year"`
+			Runtime data.Runtime `json:"runtime"`
+			Genres  []string     `json:"genres"`
+		}
+
+		row := &importRow{row: n, errors: map[string]string{}}
+		rows = append(rows, row)
+
+		d := json.NewDecoder(bytes.NewReader(line))
+		d.DisallowUnknownFields()
+
+		err := d.Decode(&input)
+		if err != nil {
+			row.errors["row"] = err.Error()
+			continue
+		}
+
+		row.movie = &data.Movies{
+			Title:   input.Title,
+			Year:    input.Year,
+			Runtime: input.Runtime,
+			Genres:  input.Genres,
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
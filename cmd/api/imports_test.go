@@ -0,0 +1,43 @@
+package main
+
+import (
+	"movie-api/internal/data"
+	"strings"
+	"testing"
+)
+
+func TestReadImportCSVRuntime(t *testing.T) {
+	app := newTestApplication(data.NewMovieMockModel())
+
+	tests := []struct {
+		cell    string
+		runtime data.Runtime
+		ok      bool
+	}{
+		{"102", 102, true},
+		{"0", 0, true},
+		{"102 mins", 102, true},
+		{"1h 42m", 102, true},
+		{"PT1H42M", 102, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		rows, err := app.readImportCSV(strings.NewReader("title,year,runtime,genres\nHeat,1995," + tt.cell + ",crime\n"))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.cell, err)
+		}
+
+		row := rows[0]
+		if _, failed := row.errors["runtime"]; failed == tt.ok {
+			t.Errorf("%q: got the row errors %v, want ok=%t", tt.cell, row.errors, tt.ok)
+			continue
+		}
+
+		if tt.ok && row.movie.Runtime != tt.runtime {
+			t.Errorf("%q: got %d, want %d", tt.cell, row.movie.Runtime, tt.runtime)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"movie-api/internal/data"
+	"movie-api/internal/validators"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// movieIncludes are the relations include= can embed into movies. Each one loads its data for a whole
+// page of movies at once, so listings don't run a query per movie.
+var movieIncludes = map[string]func(app *application, movies []*data.Movies) error{
+	"credits": (*application).includeCredits,
+}
+
+// readMovieIncludes reads the relations asked for with include=, reporting the unknown ones
+func (app *application) readMovieIncludes(qs url.Values, v *validators.Validators) []string {
+	include := app.readCSV(qs, "include", []string{})
+
+	var names []string
+	for name := range movieIncludes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, value := range include {
+		v.Check(movieIncludes[value] != nil, "include", validators.CodeUnknownValue, "unknown include value "+value+", must be one of "+strings.Join(names, ", "))
+	}
+
+	v.Check(validators.Unique(include), "include", validators.CodeDuplicate, "include values cannot be duplicated")
+
+	return include
+}
+
+// includeMovies loads the given relations into the movies
+func (app *application) includeMovies(movies []*data.Movies, include []string) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	for _, name := range include {
+		err := movieIncludes[name](app, movies)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (app *application) includeCredits(movies []*data.Movies) error {
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	credits, err := app.models.Credits.GetForMovies(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		movie.Credits = credits[movie.ID]
+		if movie.Credits == nil {
+			movie.Credits = []*data.Credit{}
+		}
+	}
+
+	return nil
+}
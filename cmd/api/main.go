@@ -1,10 +1,10 @@
 package main
 
 import (
-	"context"
 	"database/sql"
 	"expvar"
 	"flag"
+	"fmt"
 	_ "github.com/jackc/pgx/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"movie-api/internal/data"
@@ -40,6 +40,26 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	digest struct {
+		enabled  bool
+		interval time.Duration
+	}
+	conditional struct {
+		requireIfMatch bool
+	}
+	idempotency struct {
+		ttl   time.Duration
+		lease time.Duration
+	}
+	graphql struct {
+		maxDepth      int
+		maxComplexity int
+	}
+	grpc struct {
+		port int
+	}
+	errorFormat string
+	swaggerUI   bool
 }
 
 type application struct {
@@ -67,6 +87,31 @@ func main() {
 	flag.StringVar(&cfg.smtp.password, "smtp-password", "fbe40984f0556d", "SMTP password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "greemlight.team@email.com", "SMTP sender")
 
+	flag.BoolVar(&cfg.digest.enabled, "digest-enabled", true, "Enable genre digest emails")
+	flag.DurationVar(&cfg.digest.interval, "digest-interval", time.Hour, "How often due digest emails are checked")
+
+	flag.BoolVar(&cfg.conditional.requireIfMatch, "require-if-match", false, "Reject movie updates and deletes without an If-Match header")
+
+	flag.DurationVar(&cfg.idempotency.ttl, "idempotency-ttl", 24*time.Hour, "How long responses to requests with an Idempotency-Key are replayed")
+	flag.DurationVar(&cfg.idempotency.lease, "idempotency-lease", time.Minute, "How long a request with an Idempotency-Key holds its key before a retry can take it over, longer than any request runs")
+
+	flag.IntVar(&cfg.graphql.maxDepth, "graphql-max-depth", 6, "Deepest nesting of fields a GraphQL query may have")
+	flag.IntVar(&cfg.graphql.maxComplexity, "graphql-max-complexity", 2500, "Highest complexity a GraphQL query may have, counting the items list fields may return")
+
+	flag.IntVar(&cfg.grpc.port, "grpc-port", 4001, "Server port for the gRPC movie service, 0 disables it")
+
+	flag.BoolVar(&cfg.swaggerUI, "swagger-ui", false, "Serve a Swagger UI page of the OpenAPI document at /v1/docs")
+
+	cfg.errorFormat = errorFormatProblem
+	flag.Func("error-format", "Error response format, problem (RFC 7807) or legacy", func(val string) error {
+		if val != errorFormatProblem && val != errorFormatLegacy {
+			return fmt.Errorf("must be %s or %s", errorFormatProblem, errorFormatLegacy)
+		}
+
+		cfg.errorFormat = val
+		return nil
+	})
+
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
 		cfg.cors.trustedOrigins = strings.Fields(val)
 		return nil
@@ -111,18 +156,5 @@ func main() {
 }
 
 func openDB(cfg config) (*sql.DB, error) {
-	db, err := sql.Open("pgx", cfg.db.dns)
-	if err != nil {
-		return nil, err
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	err = db.PingContext(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
+	return data.OpenDB(cfg.db.dns)
 }
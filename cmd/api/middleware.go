@@ -184,6 +184,31 @@ func (app *application) requirePermissionResponse(code string, next http.Handler
 	return app.requireActivateUser(fn)
 }
 
+// allowMethod rejects the requests to a route registered outside the router that don't use the given method,
+// answering OPTIONS the way the router does
+func (app *application) allowMethod(method string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method+", "+http.MethodOptions)
+
+			if r.Method == http.MethodOptions {
+				app.optionsResponse(w, r)
+				return
+			}
+
+			app.methodNotAllowedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// optionsResponse answers an OPTIONS request, the Allow header listing the methods of the route is already set
+func (app *application) optionsResponse(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // enableCORS handlers cors request and protects of possible vulnerabilities
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -215,15 +240,16 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) metrics(next http.Handler) http.Handler {
-	var (
-		totalRequestRecieved            = expvar.NewInt("total_request_received")
-		totalResponsesSent              = expvar.NewInt("total_responses_sent")
-		totalProcessingTimeMicroseconds = expvar.NewInt("total_processing_time_ms")
+// the metrics are published once, however many times the handler is built
+var (
+	totalRequestRecieved            = expvar.NewInt("total_request_received")
+	totalResponsesSent              = expvar.NewInt("total_responses_sent")
+	totalProcessingTimeMicroseconds = expvar.NewInt("total_processing_time_ms")
 
-		totalResponsesSentByStatus = expvar.NewMap("total_responses_sent_by_status")
-	)
+	totalResponsesSentByStatus = expvar.NewMap("total_responses_sent_by_status")
+)
 
+func (app *application) metrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
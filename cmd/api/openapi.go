@@ -0,0 +1,895 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	httpr "github.com/julienschmidt/httprouter"
+	"io/fs"
+	"movie-api/internal/data"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// object is a node of the OpenAPI document
+type object = map[string]any
+
+// routeTable registers routes on the router and remembers them, the OpenAPI document is built from it
+type routeTable struct {
+	router *httpr.Router
+	routes []apiRoute
+}
+
+type apiRoute struct {
+	method string
+	path   string
+}
+
+func (t *routeTable) handle(method, path string, handler http.HandlerFunc) {
+	t.record(method, path)
+	t.router.HandlerFunc(method, path, handler)
+}
+
+// record adds a route served outside the router to the table
+func (t *routeTable) record(method, path string) {
+	t.routes = append(t.routes, apiRoute{method: method, path: path})
+}
+
+// apiOperation documents a route. Every operation answers errors as problem details, the responses listed
+// in errors are the ones worth knowing about on top of the 5xx and 429 any request can get.
+type apiOperation struct {
+	summary     string
+	description string
+	permission  string
+	public      bool
+	params      []object
+	body        object
+	status      int
+	response    object
+	errors      []int
+}
+
+var pathParamRx = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// openAPIDocument builds the OpenAPI 3.1 document of the routes. A route without an operation in
+// apiOperations, or an operation without a route, is an error so the two can't drift apart.
+func openAPIDocument(routes []apiRoute) ([]byte, error) {
+	paths := object{}
+	seen := map[string]bool{}
+
+	for _, route := range routes {
+		key := route.method + " " + route.path
+		op, ok := apiOperations[key]
+		if !ok {
+			return nil, fmt.Errorf("openapi: no operation documented for %s", key)
+		}
+		seen[key] = true
+
+		path := pathParamRx.ReplaceAllString(route.path, "{$1}")
+
+		item, ok := paths[path].(object)
+		if !ok {
+			item = object{}
+			paths[path] = item
+		}
+
+		item[strings.ToLower(route.method)] = op.document(route)
+	}
+
+	var stale []string
+	for key := range apiOperations {
+		if !seen[key] {
+			stale = append(stale, key)
+		}
+	}
+
+	if len(stale) != 0 {
+		sort.Strings(stale)
+		return nil, fmt.Errorf("openapi: operations documented for unregistered routes: %s", strings.Join(stale, ", "))
+	}
+
+	return json.Marshal(object{
+		"openapi": "3.1.0",
+		"info": object{
+			"title":   "Movie API",
+			"version": Version,
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": apiSchemas,
+			"securitySchemes": object{
+				"bearer": object{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "An authentication token from POST /v1/tokens/authentication",
+				},
+			},
+		},
+	})
+}
+
+func (op apiOperation) document(route apiRoute) object {
+	doc := object{
+		"summary":     op.summary,
+		"operationId": operationID(route),
+	}
+
+	description := op.description
+	if op.permission != "" {
+		description = strings.TrimSpace(description + "\n\nRequires the `" + op.permission + "` permission.")
+	}
+
+	if description != "" {
+		doc["description"] = description
+	}
+
+	var params []object
+	for _, match := range pathParamRx.FindAllStringSubmatch(route.path, -1) {
+		params = append(params, object{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   object{"type": "integer", "format": "int64", "minimum": 1},
+		})
+	}
+	params = append(params, op.params...)
+
+	if len(params) != 0 {
+		doc["parameters"] = params
+	}
+
+	if op.body != nil {
+		doc["requestBody"] = op.body
+	}
+
+	if op.public {
+		doc["security"] = []object{}
+	} else {
+		doc["security"] = []object{{"bearer": []string{}}}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := object{"description": http.StatusText(status)}
+	if op.response != nil {
+		success["content"] = object{"application/json": object{"schema": op.response}}
+	}
+
+	responses := object{strconv.Itoa(status): success}
+
+	errors := op.errors
+	if !op.public {
+		errors = append([]int{http.StatusUnauthorized, http.StatusForbidden}, errors...)
+	}
+
+	for _, status := range errors {
+		responses[strconv.Itoa(status)] = problemResponse(http.StatusText(status))
+	}
+
+	responses["default"] = problemResponse("An error")
+
+	doc["responses"] = responses
+
+	return doc
+}
+
+// operationID names an operation after its method and the static segments of its path
+func operationID(route apiRoute) string {
+	id := strings.ToLower(route.method)
+
+	for _, segment := range strings.Split(route.path, "/") {
+		if segment == "" || segment == "v1" {
+			continue
+		}
+
+		if strings.HasPrefix(segment, ":") {
+			segment = "by_" + segment[1:]
+		}
+
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool { return r == '_' || r == '.' }) {
+			id += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+
+	return id
+}
+
+func problemResponse(description string) object {
+	return object{
+		"description": description,
+		"content":     object{"application/problem+json": object{"schema": ref("Problem")}},
+	}
+}
+
+func ref(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+func arrayOf(items object) object {
+	return object{"type": "array", "items": items}
+}
+
+// envelopeOf is the schema of a response wrapping its members in a JSON object
+func envelopeOf(members object) object {
+	return object{"type": "object", "properties": members}
+}
+
+func jsonBody(schema object) object {
+	return object{
+		"required": true,
+		"content":  object{"application/json": object{"schema": schema}},
+	}
+}
+
+func queryParam(name, description string, schema object) object {
+	return object{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+func stringEnum(values ...string) object {
+	return object{"type": "string", "enum": values}
+}
+
+func csvParam(name, description string) object {
+	return object{
+		"name":        name,
+		"in":          "query",
+		"description": description + ", comma separated",
+		"schema":      object{"type": "string"},
+		"style":       "form",
+		"explode":     false,
+	}
+}
+
+var (
+	integerSchema   = object{"type": "integer"}
+	stringSchema    = object{"type": "string"}
+	timestampSchema = object{"type": "string", "format": "date-time"}
+	messageResponse = envelopeOf(object{"message": stringSchema})
+)
+
+// filterParams are the query parameters of the paginated listings, sort taking the given values
+func filterParams(defaultSort string, sorts ...string) []object {
+	var values []string
+	for _, s := range sorts {
+		values = append(values, s, "-"+s)
+	}
+
+	return []object{
+		queryParam("page", "Page to read, starting at 1", object{"type": "integer", "minimum": 1, "maximum": 10_000_000, "default": 1}),
+		queryParam("page_size", "Amount of records per page", object{"type": "integer", "minimum": 1, "maximum": 100, "default": 20}),
+		queryParam("sort", "Field to sort on, descending with a - prefix", object{"type": "string", "enum": values, "default": defaultSort}),
+	}
+}
+
+func withParams(params []object, extra ...object) []object {
+	return append(append([]object{}, params...), extra...)
+}
+
+var runtimeFormatParam = queryParam("runtime_format", "Format runtimes are written in", stringEnum(data.RuntimeMinutes, data.RuntimeISO8601, data.RuntimeHuman))
+
+// movieQueryParams are the filters shared by the movie listing and export
+var movieQueryParams = []object{
+	queryParam("q", "Full-text search on titles and genres, results carry a score and a highlight", object{"type": "string", "maxLength": 200}),
+	queryParam("title", "Titles containing the words", stringSchema),
+	csvParam("genres", "Genres every movie must have"),
+	csvParam("genres_any", "Genres of which movies must have at least one"),
+	csvParam("genres_exclude", "Genres movies must not have"),
+	queryParam("year_min", "Earliest year", object{"type": "integer", "minimum": 0}),
+	queryParam("year_max", "Latest year", object{"type": "integer", "minimum": 0}),
+	queryParam("runtime_min", "Shortest runtime in minutes", object{"type": "integer", "minimum": 0}),
+	queryParam("runtime_max", "Longest runtime in minutes", object{"type": "integer", "minimum": 0}),
+	queryParam("created_after", "RFC 3339 timestamp or YYYY-MM-DD date", stringSchema),
+	queryParam("created_before", "RFC 3339 timestamp or YYYY-MM-DD date", stringSchema),
+}
+
+var movieBody = object{
+	"type":     "object",
+	"required": []string{"title", "year", "runtime", "genres"},
+	"properties": object{
+		"title":   stringSchema,
+		"year":    object{"type": "integer", "minimum": 1990},
+		"runtime": ref("Runtime"),
+		"genres":  object{"type": "array", "items": stringSchema, "minItems": 1, "uniqueItems": true},
+	},
+	"additionalProperties": false,
+}
+
+var moviePatchBody = object{
+	"required": true,
+	"content": object{
+		"application/json": object{"schema": object{
+			"type":                 "object",
+			"properties":           movieBody["properties"],
+			"additionalProperties": false,
+		}},
+		mergePatchType: object{"schema": object{"type": "object", "description": "An RFC 7386 merge patch of the movie"}},
+		jsonPatchType:  object{"schema": arrayOf(ref("JSONPatchOperation"))},
+	},
+}
+
+var ifMatchParam = object{
+	"name":        "If-Match",
+	"in":          "header",
+	"description": "ETag of the movie as last read, or \"id-version\", the request fails with 412 when it changed since",
+	"schema":      stringSchema,
+}
+
+var idempotencyKeyParam = object{
+	"name":        "Idempotency-Key",
+	"in":          "header",
+	"description": "Makes retries of the request replay the first response instead of running it again",
+	"schema":      object{"type": "string", "maxLength": 255},
+}
+
+var movieResponse = envelopeOf(object{"movie": ref("Movie")})
+
+// apiOperations documents every route of routes(), keyed by method and path
+var apiOperations = map[string]apiOperation{
+	"GET /v1/healthcheck": {
+		summary: "Report the status of the API",
+		public:  true,
+		response: envelopeOf(object{
+			"status":      stringSchema,
+			"system_info": envelopeOf(object{"environment": stringSchema, "version": stringSchema}),
+		}),
+	},
+	"GET /v1/openapi.json": {
+		summary:  "Read this document",
+		public:   true,
+		response: object{"type": "object"},
+	},
+	"GET /v1/movies": {
+		summary:    "List movies",
+		permission: "movies:read",
+		description: "Pages are numbered with page or followed with the cursors of the metadata. " +
+			"Searches with q sort on score by default.",
+		params: withParams(withParams(movieQueryParams, filterParams("id", "id", "title", "year", "runtime", "rating", "score")...),
+			queryParam("cursor", "Cursor from the metadata of a previous page", stringSchema),
+			queryParam("include_total", "Count the matching movies for the metadata", object{"type": "boolean", "default": true}),
+			csvParam("facets", "Counts to add to the response: genres, year"),
+			csvParam("fields", "Fields to trim the movies to"),
+			csvParam("include", "Relations to embed into the movies: credits"),
+			runtimeFormatParam,
+		),
+		response: envelopeOf(object{
+			"movies":   arrayOf(ref("Movie")),
+			"metadata": ref("Metadata"),
+			"facets":   object{"type": "object", "additionalProperties": arrayOf(ref("FacetCount"))},
+		}),
+		errors: []int{http.StatusUnprocessableEntity},
+	},
+	"POST /v1/movies": {
+		summary:    "Create a movie",
+		permission: "movies:write",
+		params:     []object{idempotencyKeyParam, runtimeFormatParam},
+		body:       jsonBody(movieBody),
+		status:     http.StatusCreated,
+		response:   movieResponse,
+		errors:     []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity},
+	},
+	"GET /v1/movies/:id": {
+		summary:     "Read a movie",
+		permission:  "movies:read",
+		description: "The response carries an ETag, If-None-Match answers 304 when the movie didn't change.",
+		params: []object{
+			csvParam("fields", "Fields to trim the movie to"),
+			csvParam("include", "Relations to embed into the movie: credits"),
+			runtimeFormatParam,
+		},
+		response: movieResponse,
+		errors:   []int{http.StatusNotFound, http.StatusUnprocessableEntity},
+	},
+	"PATCH /v1/movies/:id": {
+		summary:     "Update a movie",
+		permission:  "movies:write",
+		description: "A JSON body sets the fields it holds, merge patches and JSON Patch documents edit the movie.",
+		params:      []object{ifMatchParam, runtimeFormatParam},
+		body:        moviePatchBody,
+		response:    movieResponse,
+		errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity},
+	},
+	"DELETE /v1/movies/:id": {
+		summary:    "Delete a movie",
+		permission: "movies:write",
+		params:     []object{ifMatchParam},
+		response:   messageResponse,
+		errors:     []int{http.StatusNotFound, http.StatusPreconditionFailed},
+	},
+	"GET /v1/movies/export": {
+		summary:    "Export the movies matching the filters",
+		permission: "movies:export",
+		params:     withParams(movieQueryParams, queryParam("format", "Format of the file", object{"type": "string", "enum": []string{"csv", "tsv", "ndjson"}, "default": "csv"})),
+		errors:     []int{http.StatusUnprocessableEntity},
+	},
+	"POST /v1/movies/import": {
+		summary:     "Import movies from a CSV or NDJSON file",
+		permission:  "movies:write",
+		description: "CSV files need a title,year,runtime,genres header, genres separated with |. Valid rows are copied in the background.",
+		params: []object{
+			queryParam("mode", "Whether movies matching on title and year are updated", object{"type": "string", "enum": []string{data.ImportInsert, data.ImportUpsert}, "default": data.ImportInsert}),
+			queryParam("dry_run", "Only validate the file", object{"type": "boolean", "default": false}),
+		},
+		body: object{
+			"required": true,
+			"content": object{
+				"text/csv":             object{"schema": stringSchema},
+				"application/x-ndjson": object{"schema": stringSchema},
+			},
+		},
+		status:   http.StatusAccepted,
+		response: envelopeOf(object{"import": ref("Import")}),
+		errors:   []int{http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity},
+	},
+	"GET /v1/movies/:id/revisions": {
+		summary:    "List the revisions of a movie",
+		permission: "movies:read",
+		params:     filterParams("-version", "version"),
+		response:   envelopeOf(object{"revisions": arrayOf(ref("Revision")), "metadata": ref("Metadata")}),
+		errors:     []int{http.StatusNotFound, http.StatusUnprocessableEntity},
+	},
+	"POST /v1/movies/:id/revisions/:version/restore": {
+		summary:    "Restore a movie to a revision",
+		permission: "movies:write",
+		params:     []object{ifMatchParam},
+		response:   movieResponse,
+		errors:     []int{http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusUnprocessableEntity},
+	},
+	"GET /v1/movies/:id/reviews": {
+		summary:    "List the reviews of a movie",
+		permission: "movies:read",
+		params:     filterParams("-created_at", "created_at", "rating"),
+		response:   envelopeOf(object{"reviews": arrayOf(ref("Review")), "metadata": ref("Metadata")}),
+		errors:     []int{http.StatusNotFound, http.StatusUnprocessableEntity},
+	},
+	"POST /v1/movies/:id/reviews": {
+		summary:    "Review a movie",
+		permission: "movies:read",
+		body: jsonBody(object{
+			"type":     "object",
+			"required": []string{"rating"},
+			"properties": object{
+				"rating": object{"type": "integer", "minimum": 1, "maximum": 10},
+				"body":   object{"type": "string", "maxLength": 5000},
+			},
+		}),
+		status:   http.StatusCreated,
+		response: envelopeOf(object{"review": ref("Review")}),
+		errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity},
+	},
+	"PATCH /v1/movies/:id/reviews/:review_id": {
+		summary:    "Update a review of yours",
+		permission: "movies:read",
+		body: jsonBody(object{
+			"type": "object",
+			"properties": object{
+				"rating": object{"type": "integer", "minimum": 1, "maximum": 10},
+				"body":   object{"type": "string", "maxLength": 5000},
+			},
+		}),
+		response: envelopeOf(object{"review": ref("Review")}),
+		errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	"DELETE /v1/movies/:id/reviews/:review_id": {
+		summary:    "Delete a review of yours",
+		permission: "movies:read",
+		response:   messageResponse,
+		errors:     []int{http.StatusNotFound},
+	},
+	"POST /v1/movies/:id/credits": {
+		summary:    "Credit a person on a movie",
+		permission: "movies:write",
+		body: jsonBody(object{
+			"type":     "object",
+			"required": []string{"person_id", "role"},
+			"properties": object{
+				"person_id": integerSchema,
+				"role":      stringEnum(data.RoleDirector, data.RoleWriter, data.RoleCast),
+				"character": object{"type": "string", "maxLength": 500, "description": "Only for cast credits"},
+			},
+		}),
+		status:   http.StatusCreated,
+		response: envelopeOf(object{"credit": ref("Credit")}),
+		errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity},
+	},
+	"DELETE /v1/movies/:id/credits/:credit_id": {
+		summary:    "Remove a credit from a movie",
+		permission: "movies:write",
+		response:   messageResponse,
+		errors:     []int{http.StatusNotFound},
+	},
+	"GET /v1/people": {
+		summary:    "List people",
+		permission: "movies:read",
+		params:     withParams(filterParams("id", "id", "name"), queryParam("name", "Names containing the words", stringSchema)),
+		response:   envelopeOf(object{"people": arrayOf(ref("Person")), "metadata": ref("Metadata")}),
+		errors:     []int{http.StatusUnprocessableEntity},
+	},
+	"POST /v1/people": {
+		summary:    "Create a person",
+		permission: "movies:write",
+		body:       jsonBody(personBody([]string{"name"})),
+		status:     http.StatusCreated,
+		response:   envelopeOf(object{"person": ref("Person")}),
+		errors:     []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	},
+	"GET /v1/people/:id": {
+		summary:    "Read a person",
+		permission: "movies:read",
+		response:   envelopeOf(object{"person": ref("Person")}),
+		errors:     []int{http.StatusNotFound},
+	},
+	"PATCH /v1/people/:id": {
+		summary:    "Update a person",
+		permission: "movies:write",
+		body:       jsonBody(personBody(nil)),
+		response:   envelopeOf(object{"person": ref("Person")}),
+		errors:     []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	"DELETE /v1/people/:id": {
+		summary:    "Delete a person",
+		permission: "movies:write",
+		response:   messageResponse,
+		errors:     []int{http.StatusNotFound},
+	},
+	"GET /v1/people/:id/movies": {
+		summary:    "List the filmography of a person",
+		permission: "movies:read",
+		params:     filterParams("-year", "id", "title", "year", "runtime", "rating"),
+		response: envelopeOf(object{
+			"movies": arrayOf(envelopeOf(object{
+				"role":      stringEnum(data.RoleDirector, data.RoleWriter, data.RoleCast),
+				"character": stringSchema,
+				"movie":     ref("Movie"),
+			})),
+			"metadata": ref("Metadata"),
+		}),
+		errors: []int{http.StatusNotFound, http.StatusUnprocessableEntity},
+	},
+	"POST /v1/users": {
+		summary:     "Register a user",
+		description: "The user gets an email with the token activating the account.",
+		public:      true,
+		params:      []object{idempotencyKeyParam},
+		body: jsonBody(object{
+			"type":     "object",
+			"required": []string{"name", "email", "password"},
+			"properties": object{
+				"name":     object{"type": "string", "maxLength": 500},
+				"email":    object{"type": "string", "format": "email"},
+				"password": object{"type": "string", "minLength": 8, "maxLength": 72},
+			},
+		}),
+		status:   http.StatusCreated,
+		response: envelopeOf(object{"user": ref("User")}),
+		errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	"PUT /v1/users/activated": {
+		summary:  "Activate a user",
+		public:   true,
+		body:     jsonBody(object{"type": "object", "required": []string{"token"}, "properties": object{"token": object{"type": "string", "minLength": 26, "maxLength": 26}}}),
+		response: envelopeOf(object{"user": ref("User")}),
+		errors:   []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	},
+	"GET /v1/users/preferences": {
+		summary:  "Read your digest preferences",
+		response: envelopeOf(object{"preferences": ref("Preferences")}),
+	},
+	"PUT /v1/users/preferences": {
+		summary: "Update your digest preferences",
+		body: jsonBody(object{
+			"type": "object",
+			"properties": object{
+				"genres":    object{"type": "array", "items": stringSchema, "maxItems": 20, "uniqueItems": true},
+				"frequency": stringEnum(data.FrequencyDaily, data.FrequencyWeekly, data.FrequencyOff),
+			},
+		}),
+		response: envelopeOf(object{"preferences": ref("Preferences")}),
+		errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	"POST /v1/tokens/authentication": {
+		summary: "Create an authentication token",
+		public:  true,
+		body: jsonBody(object{
+			"type":     "object",
+			"required": []string{"email", "password"},
+			"properties": object{
+				"email":    object{"type": "string", "format": "email"},
+				"password": stringSchema,
+			},
+		}),
+		status:   http.StatusCreated,
+		response: envelopeOf(object{"token": ref("Token")}),
+		errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity},
+	},
+	"DELETE /v1/tokens/authentication": {
+		summary:  "Revoke every authentication token of yours",
+		response: messageResponse,
+	},
+	"POST /v1/tokens/activation": {
+		summary:  "Send a new activation token",
+		public:   true,
+		body:     jsonBody(object{"type": "object", "required": []string{"email"}, "properties": object{"email": object{"type": "string", "format": "email"}}}),
+		status:   http.StatusAccepted,
+		response: messageResponse,
+		errors:   []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	},
+	"POST /v1/graphql": {
+		summary: "Run a GraphQL query or mutation",
+		public:  true,
+		description: "Queries movies with their credits and reviews, and the viewer, that is the authenticated user. " +
+			"Fields check the permissions of the routes serving the same data, so most need a token. " +
+			"Errors of the query come in the errors of a 200 response with a code in their extensions.",
+		body: jsonBody(object{
+			"type":     "object",
+			"required": []string{"query"},
+			"properties": object{
+				"query":         stringSchema,
+				"operationName": stringSchema,
+				"variables":     object{"type": "object"},
+				"extensions":    object{"type": "object"},
+			},
+		}),
+		response: object{
+			"type": "object",
+			"properties": object{
+				"data":   object{"type": []string{"object", "null"}},
+				"errors": object{"type": "array", "items": object{"type": "object"}},
+			},
+		},
+		errors: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	},
+	"GET /v1/imports/:id": {
+		summary:    "Read the progress of an import",
+		permission: "movies:write",
+		response:   envelopeOf(object{"import": ref("Import")}),
+		errors:     []int{http.StatusNotFound},
+	},
+}
+
+func personBody(required []string) object {
+	body := object{
+		"type": "object",
+		"properties": object{
+			"name":      object{"type": "string", "maxLength": 500},
+			"biography": object{"type": "string", "maxLength": 10_000},
+		},
+	}
+
+	if required != nil {
+		body["required"] = required
+	}
+
+	return body
+}
+
+// apiSchemas are the shared schemas of the document
+var apiSchemas = object{
+	"Runtime": object{
+		"description": "A runtime. Responses write it as \"102 mins\" unless runtime_format asks for PT1H42M (iso8601) " +
+			"or 1h 42m (human); requests take any of those or a whole number of minutes.",
+		"oneOf": []object{
+			{"type": "string", "format": "runtime", "examples": []string{"102 mins", "1h 42m", "PT1H42M"}},
+			{"type": "integer", "minimum": 0},
+		},
+	},
+	"Movie": object{
+		"type": "object",
+		"properties": object{
+			"id":             object{"type": "integer", "format": "int64"},
+			"title":          stringSchema,
+			"year":           object{"type": "integer", "format": "int32"},
+			"runtime":        ref("Runtime"),
+			"genres":         arrayOf(stringSchema),
+			"average_rating": object{"type": "number"},
+			"rating_count":   integerSchema,
+			"version":        integerSchema,
+			"credits":        arrayOf(ref("Credit")),
+			"score":          object{"type": "number", "description": "Relevance to q, set on searches"},
+			"highlight":      object{"type": "string", "description": "Title with the matches of q in <mark> tags, set on searches"},
+		},
+	},
+	"Credit": object{
+		"type": "object",
+		"properties": object{
+			"id":          integerSchema,
+			"movie_id":    integerSchema,
+			"person_id":   integerSchema,
+			"person_name": stringSchema,
+			"role":        stringEnum(data.RoleDirector, data.RoleWriter, data.RoleCast),
+			"character":   stringSchema,
+		},
+	},
+	"Person": object{
+		"type": "object",
+		"properties": object{
+			"id":        integerSchema,
+			"name":      stringSchema,
+			"biography": stringSchema,
+			"version":   integerSchema,
+		},
+	},
+	"Review": object{
+		"type": "object",
+		"properties": object{
+			"id":         integerSchema,
+			"movie_id":   integerSchema,
+			"user_id":    integerSchema,
+			"created_at": timestampSchema,
+			"rating":     object{"type": "integer", "minimum": 1, "maximum": 10},
+			"body":       stringSchema,
+			"version":    integerSchema,
+		},
+	},
+	"Revision": object{
+		"type": "object",
+		"properties": object{
+			"id":         integerSchema,
+			"movie_id":   integerSchema,
+			"version":    integerSchema,
+			"action":     stringSchema,
+			"actor_id":   object{"type": []string{"integer", "null"}},
+			"created_at": timestampSchema,
+			"changes": object{
+				"type":                 "object",
+				"additionalProperties": envelopeOf(object{"old": object{}, "new": object{}}),
+			},
+		},
+	},
+	"User": object{
+		"type": "object",
+		"properties": object{
+			"id":         integerSchema,
+			"created_at": timestampSchema,
+			"name":       stringSchema,
+			"email":      object{"type": "string", "format": "email"},
+			"activated":  object{"type": "boolean"},
+		},
+	},
+	"Preferences": object{
+		"type": "object",
+		"properties": object{
+			"genres":         arrayOf(stringSchema),
+			"frequency":      stringEnum(data.FrequencyDaily, data.FrequencyWeekly, data.FrequencyOff),
+			"last_digest_at": timestampSchema,
+		},
+	},
+	"Token": object{
+		"type":       "object",
+		"properties": object{"token": stringSchema, "expiry": timestampSchema},
+	},
+	"Import": object{
+		"type": "object",
+		"properties": object{
+			"id":          integerSchema,
+			"created_at":  timestampSchema,
+			"finished_at": timestampSchema,
+			"mode":        stringEnum(data.ImportInsert, data.ImportUpsert),
+			"dry_run":     object{"type": "boolean"},
+			"status":      stringSchema,
+			"total_rows":  integerSchema,
+			"valid_rows":  integerSchema,
+			"inserted":    integerSchema,
+			"updated":     integerSchema,
+			"row_errors": arrayOf(envelopeOf(object{
+				"row":    integerSchema,
+				"errors": object{"type": "object", "additionalProperties": stringSchema},
+			})),
+			"failure": stringSchema,
+		},
+	},
+	"Metadata": object{
+		"type":        "object",
+		"description": "Pagination of a listing, empty when nothing matched",
+		"properties": object{
+			"current_page":  integerSchema,
+			"page_size":     integerSchema,
+			"first_page":    integerSchema,
+			"LastPage":      integerSchema,
+			"total_records": integerSchema,
+			"next_cursor":   stringSchema,
+			"prev_cursor":   stringSchema,
+		},
+	},
+	"FacetCount": object{
+		"type": "object",
+		"properties": object{
+			"value": stringSchema,
+			"min":   integerSchema,
+			"max":   integerSchema,
+			"count": integerSchema,
+		},
+	},
+	"JSONPatchOperation": object{
+		"type":     "object",
+		"required": []string{"op", "path"},
+		"properties": object{
+			"op":    stringEnum("add", "remove", "replace", "move", "copy", "test"),
+			"path":  stringSchema,
+			"from":  stringSchema,
+			"value": object{},
+		},
+	},
+	"Problem": object{
+		"type":        "object",
+		"description": "An RFC 7807 problem. With -error-format=legacy errors are written as {\"error\": message} instead.",
+		"properties": object{
+			"type":     stringSchema,
+			"title":    stringSchema,
+			"status":   integerSchema,
+			"detail":   stringSchema,
+			"instance": stringSchema,
+			"code":     stringSchema,
+			"errors": arrayOf(envelopeOf(object{
+				"field":   stringSchema,
+				"code":    stringSchema,
+				"message": stringSchema,
+			})),
+		},
+	},
+}
+
+// apiDocument is the OpenAPI document, or the error that kept it from being built
+type apiDocument struct {
+	body []byte
+	err  error
+}
+
+// openAPIHandler serves the OpenAPI document
+func (app *application) openAPIHandler(spec *apiDocument) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if spec.err != nil {
+			app.serverErrorResponse(w, r, spec.err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec.body)
+	}
+}
+
+// swaggerUIFS holds the vendored Swagger UI files, the page loads them from /v1/docs rather than from a CDN
+//
+//go:embed swaggerui/swagger-ui.css swaggerui/swagger-ui-bundle.js
+var swaggerUIFS embed.FS
+
+// swaggerUIAssets are the files of swaggerUIFS the page links to
+var swaggerUIAssets = []string{"swagger-ui.css", "swagger-ui-bundle.js"}
+
+// swaggerUIAssetHandler serves the files of swaggerUIFS under /v1/docs/
+func (app *application) swaggerUIAssetHandler() http.Handler {
+	assets, err := fs.Sub(swaggerUIFS, "swaggerui")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.StripPrefix("/v1/docs/", http.FileServerFS(assets))
+}
+
+// swaggerUIHandler serves a Swagger UI page reading the OpenAPI document
+func (app *application) swaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>Movie API</title>
+	<link rel="stylesheet" href="/v1/docs/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="/v1/docs/swagger-ui-bundle.js"></script>
+	<script>
+		SwaggerUIBundle({url: "/v1/openapi.json", dom_id: "#swagger-ui"});
+	</script>
+</body>
+</html>
+`
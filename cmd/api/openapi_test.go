@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"movie-api/internal/data"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	handler, routes := newTestApplication(data.NewMovieMockModel()).routeHandler()
+
+	body, err := openAPIDocument(routes)
+	if err != nil {
+		t.Fatalf("the routes and apiOperations are out of sync: %v", err)
+	}
+
+	var doc struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range routes {
+		path := pathParamRx.ReplaceAllString(route.path, "{$1}")
+		if _, ok := doc.Paths[path][strings.ToLower(route.method)]; !ok {
+			t.Errorf("%s %s is missing from the document", route.method, path)
+		}
+	}
+
+	res := serve(handler, http.MethodGet, "/v1/openapi.json")
+	if res.Code != http.StatusOK {
+		t.Fatalf("GET /v1/openapi.json: got %d, want %d", res.Code, http.StatusOK)
+	}
+
+	if res.Body.String() != string(body) {
+		t.Errorf("GET /v1/openapi.json: got a different document than the routes build")
+	}
+}
+
+func TestOpenAPIDocumentOutOfSync(t *testing.T) {
+	_, routes := newTestApplication(data.NewMovieMockModel()).routeHandler()
+
+	undocumented := append(routes[:len(routes):len(routes)], apiRoute{method: http.MethodGet, path: "/v1/undocumented"})
+	if _, err := openAPIDocument(undocumented); err == nil || !strings.Contains(err.Error(), "/v1/undocumented") {
+		t.Errorf("undocumented route: got %v, want an error naming it", err)
+	}
+
+	if _, err := openAPIDocument(routes[1:]); err == nil || !strings.Contains(err.Error(), routes[0].path) {
+		t.Errorf("unregistered route: got %v, want an error naming %s", err, routes[0].path)
+	}
+}
+
+func TestSwaggerUI(t *testing.T) {
+	app := newTestApplication(data.NewMovieMockModel())
+	app.config.swaggerUI = true
+	handler := app.routes()
+
+	res := serve(handler, http.MethodGet, "/v1/docs")
+	if res.Code != http.StatusOK || strings.Contains(res.Body.String(), "https://") {
+		t.Fatalf("GET /v1/docs: got %d, want the page with no remote assets", res.Code)
+	}
+
+	for _, name := range swaggerUIAssets {
+		if !strings.Contains(res.Body.String(), `"/v1/docs/`+name+`"`) {
+			t.Errorf("GET /v1/docs: the page doesn't link to %s", name)
+		}
+
+		res := serve(handler, http.MethodGet, "/v1/docs/"+name)
+		if res.Code != http.StatusOK || res.Body.Len() == 0 {
+			t.Errorf("GET /v1/docs/%s: got %d with %d bytes", name, res.Code, res.Body.Len())
+		}
+	}
+
+	if res := serve(handler, http.MethodGet, "/v1/docs/LICENSE"); res.Code != http.StatusNotFound {
+		t.Errorf("GET /v1/docs/LICENSE: got %d, want %d", res.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"movie-api/internal/data"
+	"movie-api/internal/jsonpatch"
+	"movie-api/internal/validators"
+	"net/http"
+	"strings"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// moviePatchDocument is the part of a movie patches apply to
+type moviePatchDocument struct {
+	Title   string       `json:"title"`
+	Year    int32        `json:"year"`
+	Runtime data.Runtime `json:"runtime"`
+	Genres  []string     `json:"genres"`
+}
+
+// patchMovie applies a merge patch or a JSON patch from the request body to the movie. A patch that can't
+// be applied is reported in v keyed by the JSON Pointer it failed at, other errors are about the body itself.
+func (app *application) patchMovie(w http.ResponseWriter, r *http.Request, mediaType string, movie *data.Movies, v *validators.Validators) error {
+	var body json.RawMessage
+
+	err := app.readJSON(w, r, &body)
+	if err != nil {
+		return err
+	}
+
+	var doc any
+
+	js, err := json.Marshal(moviePatchDocument{Title: movie.Title, Year: movie.Year, Runtime: movie.Runtime, Genres: movie.Genres})
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(js, &doc)
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case mergePatchType:
+		var patch any
+
+		err = json.Unmarshal(body, &patch)
+		if err != nil {
+			return err
+		}
+
+		doc = jsonpatch.Merge(doc, patch)
+	case jsonPatchType:
+		var ops []jsonpatch.Operation
+
+		err = json.Unmarshal(body, &ops)
+		if err != nil {
+			return errors.New("body must be an array of JSON Patch operations")
+		}
+
+		var patchErrors []*jsonpatch.Error
+
+		doc, patchErrors = jsonpatch.Apply(doc, ops)
+		for _, e := range patchErrors {
+			v.AddErr(pointerKey(e.Path), validators.CodePatchFailed, fmt.Sprintf("operation %d (%s): %s", e.Index, e.Op, e.Message))
+		}
+
+		if !v.IsValid() {
+			return nil
+		}
+	}
+
+	js, err = json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	var patched moviePatchDocument
+
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.DisallowUnknownFields()
+
+	var unmarshalTypeError *json.UnmarshalTypeError
+
+	err = d.Decode(&patched)
+	if err != nil {
+		switch {
+		case errors.As(err, &unmarshalTypeError) && unmarshalTypeError.Field != "":
+			v.AddErr(pointerKey("/"+unmarshalTypeError.Field), validators.CodeInvalid, "must be a "+unmarshalTypeError.Type.String())
+		case strings.HasPrefix(err.Error(), "json: unknown field"):
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			v.AddErr(pointerKey("/"+field), validators.CodeInvalid, "is not a field of a movie")
+		default:
+			v.AddErr(pointerKey(""), validators.CodeInvalid, "the patched document is not a valid movie: "+err.Error())
+		}
+
+		return nil
+	}
+
+	movie.Title = patched.Title
+	movie.Year = patched.Year
+	movie.Runtime = patched.Runtime
+	movie.Genres = patched.Genres
+
+	return nil
+}
+
+// pointerKey is the validation error key of a JSON Pointer, the whole document being spelled "/"
+func pointerKey(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+
+	return pointer
+}
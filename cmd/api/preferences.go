@@ -0,0 +1,68 @@
+package main
+
+import (
+	"movie-api/internal/data"
+	"movie-api/internal/validators"
+	"net/http"
+)
+
+func (app *application) showPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	preferences, err := app.models.Preferences.GetForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"preferences": preferences}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	preferences, err := app.models.Preferences.GetForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Genres    []string `json:"genres"`
+		Frequency *string  `json:"frequency"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.readBodyErrorResponse(w, r, err)
+		return
+	}
+
+	if input.Genres != nil {
+		preferences.Genres = input.Genres
+	}
+
+	if input.Frequency != nil {
+		preferences.Frequency = *input.Frequency
+	}
+
+	v := validators.New()
+	if data.ValidatePreferences(v, preferences); !v.IsValid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.models.Preferences.Upsert(preferences)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"preferences": preferences}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
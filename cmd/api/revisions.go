@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"movie-api/internal/data"
+	"movie-api/internal/validators"
+	"net/http"
+)
+
+func (app *application) listMovieRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.getId(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validators.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-version")
+	input.Filters.SortList = []string{"version", "-version"}
+
+	if data.ValidateFilters(v, input.Filters); !v.IsValid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	revisions, metadata, err := app.models.Revisions.GetAllForMovie(id, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// restoreMovieRevisionHandler brings a movie back to the state it had at the given version. The restore is
+// written as a regular update, so it goes through the optimistic version check and gets its own revision.
+func (app *application) restoreMovieRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.getId(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := app.getVersion(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	revision, err := app.models.Revisions.Get(id, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if revision.Action == data.RevisionDelete {
+		v := validators.New()
+		v.AddErr("version", validators.CodeInvalid, "a delete revision cannot be restored")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	movie, err := app.models.Movies.GetMovie(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkIfMatch(w, r, movieETag(movie), movieVersionETag(movie)) {
+		return
+	}
+
+	revision.Apply(movie)
+
+	v := validators.New()
+	if data.CheckValidators(v, movie); !v.IsValid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	movie, err = app.models.Movies.UpdateMovie(movie, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", movieETag(movie))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
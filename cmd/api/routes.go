@@ -7,20 +7,93 @@ import (
 )
 
 func (app *application) routes() http.Handler {
+	handler, _ := app.routeHandler()
+	return handler
+}
+
+// routeHandler builds the handler of the API and lists the routes it serves
+func (app *application) routeHandler() (http.Handler, []apiRoute) {
 	router := httpr.New()
 
-	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheck)
-	router.HandlerFunc(http.MethodGet, "/v1/movies", app.requirePermissionResponse("movies:read", app.getAllMovies))
-	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requirePermissionResponse("movies:write", app.createMovieHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.requirePermissionResponse("movies:read", app.getMovieHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.requirePermissionResponse("movies:write", app.updateMovieHandler))
+	// the router sets the Allow header before answering a 405 or an OPTIONS request
+	router.NotFound = http.HandlerFunc(app.notFoundResponse)
+	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+	router.GlobalOPTIONS = http.HandlerFunc(app.optionsResponse)
+
+	routes := &routeTable{router: router}
+
+	var spec apiDocument
+
+	routes.handle(http.MethodGet, "/v1/healthcheck", app.healthcheck)
+	routes.handle(http.MethodGet, "/v1/openapi.json", app.openAPIHandler(&spec))
+	routes.handle(http.MethodGet, "/v1/movies", app.requirePermissionResponse("movies:read", app.getAllMovies))
+	routes.handle(http.MethodPost, "/v1/movies", app.requirePermissionResponse("movies:write", app.idempotent(app.createMovieHandler)))
+	routes.handle(http.MethodGet, "/v1/movies/:id", app.requirePermissionResponse("movies:read", app.getMovieHandler))
+	routes.handle(http.MethodPatch, "/v1/movies/:id", app.requirePermissionResponse("movies:write", app.updateMovieHandler))
+	routes.handle(http.MethodDelete, "/v1/movies/:id", app.requirePermissionResponse("movies:write", app.deleteMovieHandler))
+	routes.handle(http.MethodGet, "/v1/movies/:id/revisions", app.requirePermissionResponse("movies:read", app.listMovieRevisionsHandler))
+	routes.handle(http.MethodPost, "/v1/movies/:id/revisions/:version/restore", app.requirePermissionResponse("movies:write", app.restoreMovieRevisionHandler))
+	routes.handle(http.MethodGet, "/v1/movies/:id/reviews", app.requirePermissionResponse("movies:read", app.listMovieReviewsHandler))
+	routes.handle(http.MethodPost, "/v1/movies/:id/reviews", app.requirePermissionResponse("movies:read", app.createMovieReviewHandler))
+	routes.handle(http.MethodPatch, "/v1/movies/:id/reviews/:review_id", app.requirePermissionResponse("movies:read", app.updateMovieReviewHandler))
+	routes.handle(http.MethodDelete, "/v1/movies/:id/reviews/:review_id", app.requirePermissionResponse("movies:read", app.deleteMovieReviewHandler))
+	routes.handle(http.MethodPost, "/v1/movies/:id/credits", app.requirePermissionResponse("movies:write", app.createMovieCreditHandler))
+	routes.handle(http.MethodDelete, "/v1/movies/:id/credits/:credit_id", app.requirePermissionResponse("movies:write", app.deleteMovieCreditHandler))
+
+	routes.handle(http.MethodGet, "/v1/people", app.requirePermissionResponse("movies:read", app.listPeopleHandler))
+	routes.handle(http.MethodPost, "/v1/people", app.requirePermissionResponse("movies:write", app.createPersonHandler))
+	routes.handle(http.MethodGet, "/v1/people/:id", app.requirePermissionResponse("movies:read", app.showPersonHandler))
+	routes.handle(http.MethodPatch, "/v1/people/:id", app.requirePermissionResponse("movies:write", app.updatePersonHandler))
+	routes.handle(http.MethodDelete, "/v1/people/:id", app.requirePermissionResponse("movies:write", app.deletePersonHandler))
+	routes.handle(http.MethodGet, "/v1/people/:id/movies", app.requirePermissionResponse("movies:read", app.listPersonMoviesHandler))
+
+	routes.handle(http.MethodPost, "/v1/users", app.idempotent(app.registerUserHandler))
+
+	routes.handle(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	routes.handle(http.MethodPost, "/v1/tokens/activation", app.createActivationTokenHandler)
+	routes.handle(http.MethodDelete, "/v1/tokens/authentication", app.requireAuthenticatedUser(app.revokeAuthenticationTokensHandler))
+	routes.handle(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	routes.handle(http.MethodGet, "/v1/users/preferences", app.requireActivateUser(app.showPreferencesHandler))
+	routes.handle(http.MethodPut, "/v1/users/preferences", app.requireActivateUser(app.updatePreferencesHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
+	routes.handle(http.MethodGet, "/v1/imports/:id", app.requirePermissionResponse("movies:write", app.showImportHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/tokens/activation", app.createActivationTokenHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	// the fields of the GraphQL schema check permissions themselves, viewer works without a token
+	schema, err := app.graphqlSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	routes.handle(http.MethodPost, "/v1/graphql", app.graphqlHandler(schema))
 
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
-	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
+
+	if app.config.swaggerUI {
+		router.HandlerFunc(http.MethodGet, "/v1/docs", app.swaggerUIHandler)
+
+		assets := app.swaggerUIAssetHandler()
+		for _, name := range swaggerUIAssets {
+			router.Handler(http.MethodGet, "/v1/docs/"+name, assets)
+		}
+	}
+
+	// httprouter can't register a static segment next to the :id wildcard of /v1/movies/:id,
+	// so the collection actions on movies are matched before the request reaches the router
+	mux := http.NewServeMux()
+	mux.Handle("/v1/movies/export", app.allowMethod(http.MethodGet, app.requirePermissionResponse("movies:export", app.exportMoviesHandler)))
+	mux.Handle("/v1/movies/import", app.allowMethod(http.MethodPost, app.requirePermissionResponse("movies:write", app.importMoviesHandler)))
+	mux.Handle("/", router)
+
+	routes.record(http.MethodGet, "/v1/movies/export")
+	routes.record(http.MethodPost, "/v1/movies/import")
+
+	// a route missing from apiOperations fails the tests, the rest of the API is still served without the document
+	spec.body, spec.err = openAPIDocument(routes.routes)
+	if spec.err != nil {
+		app.logger.PrintError(spec.err, nil)
+	}
+
+	handler := app.metrics(app.recoverPanic(app.negotiateContent(app.enableCORS(app.rateLimit(app.authenticate(mux))))))
+
+	return handler, routes.routes
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"golang.org/x/time/rate"
+	"io"
+	"movie-api/internal/data"
+	"movie-api/internal/jsonlog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestApplication returns an application answering with problem details that logs nowhere and
+// doesn't rate limit
+func newTestApplication(models data.Models) *application {
+	app := &application{
+		logger: jsonlog.New(io.Discard, jsonlog.LevelOff),
+		models: models,
+	}
+	app.config.errorFormat = errorFormatProblem
+	app.config.limiter.rps = float64(rate.Inf)
+
+	return app
+}
+
+var routeParamRx = regexp.MustCompile(`:[A-Za-z_]+`)
+
+func TestRoutes(t *testing.T) {
+	handler, routes := newTestApplication(data.NewMovieMockModel()).routeHandler()
+
+	// the methods registered on each path, the path parameters filled in
+	methods := make(map[string][]string)
+	for _, route := range routes {
+		path := routeParamRx.ReplaceAllString(route.path, "1")
+		methods[path] = append(methods[path], route.method)
+	}
+
+	for _, route := range routes {
+		path := routeParamRx.ReplaceAllString(route.path, "1")
+
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			res := serve(handler, route.method, path)
+			if res.Code == http.StatusNotFound || res.Code == http.StatusMethodNotAllowed {
+				t.Errorf("got %d, want the route to be served", res.Code)
+			}
+		})
+	}
+
+	for path, allowed := range methods {
+		t.Run("OPTIONS "+path, func(t *testing.T) {
+			res := serve(handler, http.MethodOptions, path)
+			if res.Code != http.StatusNoContent {
+				t.Errorf("status: got %d, want %d", res.Code, http.StatusNoContent)
+			}
+
+			checkAllow(t, res, allowed)
+		})
+
+		method := unregisteredMethod(allowed)
+
+		t.Run(method+" "+path, func(t *testing.T) {
+			res := serve(handler, method, path)
+			if res.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status: got %d, want %d", res.Code, http.StatusMethodNotAllowed)
+			}
+
+			checkAllow(t, res, allowed)
+			checkProblem(t, res, http.StatusMethodNotAllowed, "method_not_allowed")
+		})
+	}
+
+	for _, path := range []string{"/", "/v1", "/v1/films", "/v1/movies/1/cast", "/v1/users/preferences/1"} {
+		t.Run("GET "+path, func(t *testing.T) {
+			res := serve(handler, http.MethodGet, path)
+			if res.Code != http.StatusNotFound {
+				t.Fatalf("status: got %d, want %d", res.Code, http.StatusNotFound)
+			}
+
+			checkProblem(t, res, http.StatusNotFound, "not_found")
+		})
+	}
+}
+
+func serve(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+
+	return res
+}
+
+func unregisteredMethod(allowed []string) string {
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodPost, http.MethodGet} {
+		if !slices.Contains(allowed, method) {
+			return method
+		}
+	}
+
+	return http.MethodTrace
+}
+
+func checkAllow(t *testing.T, res *httptest.ResponseRecorder, want []string) {
+	t.Helper()
+
+	allow := strings.Split(res.Header().Get("Allow"), ", ")
+	for _, method := range append(want, http.MethodOptions) {
+		if !slices.Contains(allow, method) {
+			t.Errorf("Allow: got %q, want %s in it", res.Header().Get("Allow"), method)
+		}
+	}
+}
+
+func checkProblem(t *testing.T, res *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if got := res.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type: got %q, want application/problem+json", got)
+	}
+
+	var p problem
+	if err := json.Unmarshal(res.Body.Bytes(), &p); err != nil {
+		t.Fatalf("body %q: %v", res.Body.String(), err)
+	}
+
+	if p.Status != status || p.Code != code {
+		t.Errorf("body: got status %d and code %q, want %d and %q", p.Status, p.Code, status, code)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"google.golang.org/grpc"
 	"net/http"
 	"os"
 	"os/signal"
@@ -21,8 +22,23 @@ func (app *application) serve() error {
 		IdleTimeout:  3 * time.Second,
 	}
 
+	// the gRPC server is started first so the shutdown below always finds it
+	var grpcServer *grpc.Server
+
+	if app.config.grpc.port != 0 {
+		var err error
+
+		grpcServer, err = app.serveGRPC()
+		if err != nil {
+			return err
+		}
+	}
+
 	shutdownError := make(chan error)
 
+	// stopWorkers tells the background loops to return once the running pass is done
+	stopWorkers := make(chan struct{})
+
 	// goroutine
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -37,6 +53,10 @@ func (app *application) serve() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
+		if grpcServer != nil {
+			stopGRPC(ctx, grpcServer)
+		}
+
 		err := server.Shutdown(ctx)
 		if err != nil {
 			shutdownError <- err
@@ -46,10 +66,20 @@ func (app *application) serve() error {
 			"addr": server.Addr,
 		})
 
+		close(stopWorkers)
+
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
 
+	if app.config.digest.enabled {
+		app.wg.Add(1)
+		go app.runDigests(stopWorkers)
+	}
+
+	app.wg.Add(1)
+	go app.runIdempotencyCleanup(stopWorkers)
+
 	app.logger.PrintInfo("starting server", map[string]string{
 		"addr": server.Addr,
 		"env":  app.config.environment,
@@ -57,6 +87,14 @@ func (app *application) serve() error {
 
 	err := server.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
+		// the HTTP server never ran, the gRPC one mustn't be left serving without it
+		if grpcServer != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			stopGRPC(ctx, grpcServer)
+		}
+
 		return err
 	}
 
@@ -15,13 +15,14 @@ func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *h
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
+		app.readBodyErrorResponse(w, r, err)
 		return
 	}
 
 	v := validators.New()
 
 	if data.ValidateEmail(v, input.Email); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -29,8 +30,8 @@ func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *h
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
-			v.AddErr("email", "no matching email address found")
-			app.failedValidationResponse(w, v.Errors)
+			v.AddErr("email", validators.CodeInvalid, "no matching email address found")
+			app.failedValidationResponse(w, r, v)
 		default:
 			app.serverErrorResponse(w, r, err)
 
@@ -39,8 +40,8 @@ func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *h
 	}
 
 	if user.Activated {
-		v.AddErr("email", "user has already been activated")
-		app.failedValidationResponse(w, v.Errors)
+		v.AddErr("email", validators.CodeInvalid, "user has already been activated")
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -77,7 +78,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w)
+		app.readBodyErrorResponse(w, r, err)
 		return
 	}
 
@@ -86,7 +87,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	data.ValidatePasswordPlaintext(v, input.Password)
 
 	if !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -125,3 +126,20 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 }
+
+// revokeAuthenticationTokensHandler signs the user out everywhere, every authentication token of theirs
+// stops working, the one of the request included
+func (app *application) revokeAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	err := app.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your authentication tokens were revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
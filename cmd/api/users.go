@@ -17,7 +17,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		app.readBodyErrorResponse(w, r, err)
 		return
 	}
 
@@ -36,7 +36,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	v := validators.New()
 
 	if data.ValidateUser(v, user); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -44,8 +44,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
-			v.AddErr("email", "a user with this email address already exists")
-			app.failedValidationResponse(w, v.Errors)
+			v.AddErr("email", validators.CodeDuplicate, "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -89,14 +89,14 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w)
+		app.readBodyErrorResponse(w, r, err)
 		return
 	}
 
 	v := validators.New()
 
 	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.IsValid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -104,8 +104,8 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			v.AddErr("token", "invalid or expired activation token")
-			app.failedValidationResponse(w, v.Errors)
+			v.AddErr("token", validators.CodeInvalid, "invalid or expired activation token")
+			app.failedValidationResponse(w, r, v)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
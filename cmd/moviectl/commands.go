@@ -0,0 +1,332 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"movie-api/internal/data"
+	"movie-api/pkg/client"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (ctl *moviectl) login(args []string) error {
+	fs := ctl.flags("login")
+	email := fs.String("email", ctl.cfg.Email, "Email of the account")
+	password := fs.String("password", "", "Password of the account, asked for when not given")
+
+	_, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *email == "" {
+		*email, err = ctl.prompt("Email")
+		if err != nil {
+			return err
+		}
+	}
+
+	if *password == "" {
+		*password, err = ctl.prompt("Password")
+		if err != nil {
+			return err
+		}
+	}
+
+	token, err := ctl.client.Tokens.Authenticate(ctl.ctx, *email, *password)
+	if err != nil {
+		return err
+	}
+
+	ctl.cfg.Email = *email
+	ctl.cfg.Token = token.Plaintext
+	ctl.cfg.TokenExpiry = token.Expiry
+
+	err = ctl.cfg.save(ctl.configPath)
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.message(fmt.Sprintf("logged in to %s as %s until %s", ctl.cfg.API, *email, token.Expiry.Format("2006-01-02 15:04")))
+}
+
+func (ctl *moviectl) listMovies(args []string) error {
+	var filters client.MovieFilters
+
+	fs := ctl.flags("movies list")
+	fs.StringVar(&filters.Search, "q", "", "Full-text search")
+	fs.StringVar(&filters.Title, "title", "", "Titles containing the words")
+	genres := fs.String("genres", "", "Genres every movie must have, comma separated")
+	fs.IntVar(&filters.YearMin, "year-min", 0, "Earliest year")
+	fs.IntVar(&filters.YearMax, "year-max", 0, "Latest year")
+	fs.StringVar(&filters.Sort, "sort", "", "Field to sort on, descending with a - prefix")
+	fs.IntVar(&filters.Page, "page", 0, "Page to read")
+	fs.IntVar(&filters.PageSize, "page-size", 0, "Movies per page")
+	all := fs.Bool("all", false, "Read every page")
+
+	_, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *genres != "" {
+		filters.Genres = strings.Split(*genres, ",")
+	}
+
+	var movies []*client.Movie
+	var value any
+
+	if *all {
+		it := ctl.client.Movies.Iterate(ctl.ctx, filters)
+		for it.Next() {
+			movies = append(movies, it.Movie())
+		}
+
+		if it.Err() != nil {
+			return it.Err()
+		}
+
+		value = map[string]any{"movies": movies}
+	} else {
+		var metadata client.Metadata
+
+		movies, metadata, err = ctl.client.Movies.List(ctl.ctx, filters)
+		if err != nil {
+			return err
+		}
+
+		value = map[string]any{"movies": movies, "metadata": metadata}
+	}
+
+	return ctl.out.print(value, movieTable(movies...))
+}
+
+func (ctl *moviectl) getMovie(args []string) error {
+	fs := ctl.flags("movies get")
+	include := fs.String("include", "", "Relations to embed, comma separated: credits")
+
+	id, err := idArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var includes []string
+	if *include != "" {
+		includes = strings.Split(*include, ",")
+	}
+
+	movie, err := ctl.client.Movies.Get(ctl.ctx, id, includes...)
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.print(movie, movieTable(movie))
+}
+
+func (ctl *moviectl) createMovie(args []string) error {
+	fs := ctl.flags("movies create")
+	title := fs.String("title", "", "Title")
+	year := fs.Int("year", 0, "Year")
+	runtime := fs.String("runtime", "", "Runtime, in minutes or like 1h 42m")
+	genres := fs.String("genres", "", "Genres, comma separated")
+	idempotencyKey := fs.String("idempotency-key", "", "Key making a retried create return the movie of the first one")
+
+	_, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	input := client.MovieInput{Title: *title, Year: int32(*year)}
+
+	input.Runtime, err = parseRuntime(*runtime)
+	if err != nil {
+		return err
+	}
+
+	if *genres != "" {
+		input.Genres = strings.Split(*genres, ",")
+	}
+
+	movie, err := ctl.client.Movies.Create(ctl.ctx, input, *idempotencyKey)
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.print(movie, movieTable(movie))
+}
+
+func (ctl *moviectl) updateMovie(args []string) error {
+	fs := ctl.flags("movies update")
+	title := fs.String("title", "", "Title")
+	year := fs.Int("year", 0, "Year")
+	runtime := fs.String("runtime", "", "Runtime, in minutes or like 1h 42m")
+	genres := fs.String("genres", "", "Genres, comma separated")
+	version := fs.Int("version", 0, "Only update the movie at this version")
+
+	id, err := idArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	update := client.MovieUpdate{Version: int32(*version)}
+
+	if isSet(fs, "title") {
+		update.Title = title
+	}
+
+	if isSet(fs, "year") {
+		y := int32(*year)
+		update.Year = &y
+	}
+
+	if isSet(fs, "runtime") {
+		r, err := parseRuntime(*runtime)
+		if err != nil {
+			return err
+		}
+		update.Runtime = &r
+	}
+
+	if isSet(fs, "genres") {
+		update.Genres = strings.Split(*genres, ",")
+	}
+
+	movie, err := ctl.client.Movies.Update(ctl.ctx, id, update)
+	if err != nil {
+		if client.IsConflict(err) {
+			return fmt.Errorf("%w (the movie changed, get it again and retry)", err)
+		}
+		return err
+	}
+
+	return ctl.out.print(movie, movieTable(movie))
+}
+
+func (ctl *moviectl) deleteMovie(args []string) error {
+	fs := ctl.flags("movies delete")
+	version := fs.Int("version", 0, "Only delete the movie at this version")
+
+	id, err := idArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.client.Movies.Delete(ctl.ctx, id, int32(*version))
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.message(fmt.Sprintf("movie %d deleted", id))
+}
+
+func (ctl *moviectl) activateUser(args []string) error {
+	fs := ctl.flags("users activate")
+
+	positional, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		fs.Usage()
+		return errors.New("users activate takes the activation token")
+	}
+
+	user, err := ctl.client.Users.Activate(ctl.ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.print(user, table{
+		header: []string{"ID", "NAME", "EMAIL", "ACTIVATED"},
+		rows:   [][]string{{strconv.FormatInt(user.ID, 10), user.Name, user.Email, strconv.FormatBool(user.Activated)}},
+	})
+}
+
+// revokeTokens revokes every authentication token of the logged in user and forgets the one of the config
+func (ctl *moviectl) revokeTokens(args []string) error {
+	fs := ctl.flags("tokens revoke")
+
+	_, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if ctl.cfg.Token == "" {
+		return errors.New("not logged in, run moviectl login first")
+	}
+
+	err = ctl.client.Tokens.Revoke(ctl.ctx)
+	if err != nil {
+		return err
+	}
+
+	ctl.cfg.Token = ""
+	ctl.cfg.TokenExpiry = time.Time{}
+
+	err = ctl.cfg.save(ctl.configPath)
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.message("authentication tokens revoked")
+}
+
+// idArg parses the flags of a command taking a movie id and returns the id
+func idArg(fs *flag.FlagSet, args []string) (int64, error) {
+	positional, err := parse(fs, args)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(positional) != 1 {
+		fs.Usage()
+		return 0, errors.New("a movie id is required")
+	}
+
+	id, err := strconv.ParseInt(positional[0], 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid movie id %q", positional[0])
+	}
+
+	return id, nil
+}
+
+func parseRuntime(s string) (client.Runtime, error) {
+	if minutes, err := strconv.Atoi(s); err == nil {
+		return client.Runtime(minutes), nil
+	}
+
+	runtime, err := data.ParseRuntime(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid runtime %q, give minutes or a duration like 1h 42m", s)
+	}
+
+	return runtime, nil
+}
+
+// movieTable lays movies out one per row
+func movieTable(movies ...*client.Movie) table {
+	t := table{header: []string{"ID", "TITLE", "YEAR", "RUNTIME", "GENRES", "RATING", "VERSION"}}
+
+	for _, m := range movies {
+		rating := "-"
+		if m.RatingCount != 0 {
+			rating = fmt.Sprintf("%.1f (%d)", m.AverageRating, m.RatingCount)
+		}
+
+		t.rows = append(t.rows, []string{
+			strconv.FormatInt(m.ID, 10),
+			m.Title,
+			strconv.Itoa(int(m.Year)),
+			m.Runtime.Format(data.RuntimeHuman),
+			strings.Join(m.Genres, ", "),
+			rating,
+			strconv.Itoa(int(m.Version)),
+		})
+	}
+
+	return t
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// config is what moviectl remembers between runs, written by login
+type config struct {
+	API         string    `json:"api"`
+	Token       string    `json:"token,omitempty"`
+	TokenExpiry time.Time `json:"token_expiry"`
+	Email       string    `json:"email,omitempty"`
+}
+
+const defaultAPI = "http://localhost:4000"
+
+// defaultConfigPath is moviectl/config.json in the user config directory, ~/.config on Linux
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".moviectl.json"
+	}
+
+	return filepath.Join(dir, "moviectl", "config.json")
+}
+
+// loadConfig reads the config file, a missing one is an empty config
+func loadConfig(path string) (*config, error) {
+	cfg := &config{API: defaultAPI}
+
+	js, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(js, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// save writes the config file, readable by the user only since it holds a token
+func (cfg *config) save(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	js, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(js, '\n'), 0o600)
+}
@@ -0,0 +1,214 @@
+// moviectl is a command-line client of the movie API. It talks to the API over HTTP, so it works against any
+// deployment; login keeps the token in a config file for the next commands.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"movie-api/pkg/client"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// command is a subcommand, named with its group like "movies list"
+type command struct {
+	usage string
+	run   func(ctl *moviectl, args []string) error
+}
+
+var commands = map[string]command{
+	"login":          {"login -email EMAIL", (*moviectl).login},
+	"movies list":    {"movies list [-q TEXT] [-genres a,b] [-year-min N] [-year-max N] [-sort FIELD] [-page N] [-page-size N] [-all]", (*moviectl).listMovies},
+	"movies get":     {"movies get ID [-include credits]", (*moviectl).getMovie},
+	"movies create":  {"movies create -title TITLE -year N -runtime RUNTIME -genres a,b [-idempotency-key KEY]", (*moviectl).createMovie},
+	"movies update":  {"movies update ID [-title TITLE] [-year N] [-runtime RUNTIME] [-genres a,b] [-version N]", (*moviectl).updateMovie},
+	"movies delete":  {"movies delete ID [-version N]", (*moviectl).deleteMovie},
+	"users activate": {"users activate TOKEN", (*moviectl).activateUser},
+	"tokens revoke":  {"tokens revoke", (*moviectl).revokeTokens},
+}
+
+type moviectl struct {
+	ctx        context.Context
+	cfg        *config
+	configPath string
+	client     *client.Client
+	out        printer
+	stdin      *bufio.Reader
+	stderr     io.Writer
+
+	// usage is the usage line of the command being run
+	usage string
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "moviectl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("moviectl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr, fs) }
+
+	configPath := fs.String("config", defaultConfigPath(), "Config file holding the API address and the token of login")
+	api := fs.String("api", "", "API address, overrides the one of the config file")
+	output := fs.String("o", outputTable, "Output format: table, json or yaml")
+	timeout := fs.Duration("timeout", 30*time.Second, "Timeout of a command")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *output != outputTable && *output != outputJSON && *output != outputYAML {
+		return fmt.Errorf("unknown output format %q, must be table, json or yaml", *output)
+	}
+
+	name, rest := commandName(fs.Args())
+	cmd, ok := commands[name]
+	if !ok {
+		fs.Usage()
+		if name == "" {
+			return errors.New("no command given")
+		}
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", *configPath, err)
+	}
+
+	if *api != "" {
+		cfg.API = *api
+	}
+
+	var opts []client.Option
+	if cfg.Token != "" {
+		opts = append(opts, client.WithToken(cfg.Token))
+	}
+
+	c, err := client.New(cfg.API, opts...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	ctl := &moviectl{
+		ctx:        ctx,
+		cfg:        cfg,
+		configPath: *configPath,
+		client:     c,
+		out:        printer{out: stdout, format: *output},
+		stdin:      bufio.NewReader(stdin),
+		stderr:     stderr,
+		usage:      cmd.usage,
+	}
+
+	return cmd.run(ctl, rest)
+}
+
+// commandName takes the command off the arguments, one word for login and two for the others
+func commandName(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	if _, ok := commands[args[0]]; ok {
+		return args[0], args[1:]
+	}
+
+	if len(args) == 1 {
+		return args[0], nil
+	}
+
+	return args[0] + " " + args[1], args[2:]
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: moviectl [-config FILE] [-api URL] [-o table|json|yaml] COMMAND")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "flags:")
+	fs.PrintDefaults()
+}
+
+// flags returns the flag set of a subcommand, its errors written where moviectl writes its own
+func (ctl *moviectl) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("moviectl "+name, flag.ContinueOnError)
+	fs.SetOutput(ctl.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(ctl.stderr, "usage: moviectl "+ctl.usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parse parses the flags of a subcommand, which may come after its positional arguments, and returns the
+// positional ones
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// isSet reports whether a flag was given on the command line
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+// prompt asks for a value on stdin
+func (ctl *moviectl) prompt(label string) (string, error) {
+	fmt.Fprint(ctl.stderr, label+": ")
+
+	line, err := ctl.stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// table is the tabular form of a result, the json and yaml outputs write the value itself
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// printer writes the results of the commands in the output format picked with -o
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+func (p printer) print(value any, t table) error {
+	switch p.format {
+	case outputJSON:
+		enc := json.NewEncoder(p.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case outputYAML:
+		js, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		d := json.NewDecoder(bytes.NewReader(js))
+		d.UseNumber()
+
+		var generic any
+		err = d.Decode(&generic)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		writeYAML(&buf, generic, 0)
+		_, err = p.out.Write(buf.Bytes())
+		return err
+	default:
+		w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+
+		if t.header != nil {
+			fmt.Fprintln(w, strings.Join(t.header, "\t"))
+		}
+
+		for _, row := range t.rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+
+		return w.Flush()
+	}
+}
+
+// message prints the outcome of a command that returns nothing else
+func (p printer) message(text string) error {
+	return p.print(map[string]string{"message": text}, table{rows: [][]string{{text}}})
+}
+
+var yamlPlainKeyRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// writeYAML writes a decoded JSON value as a YAML block. Strings are always double quoted, the JSON escapes
+// are valid in YAML, so no string is mistaken for a number, a boolean or null.
+func writeYAML(buf *bytes.Buffer, value any, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	switch value := value.(type) {
+	case map[string]any:
+		if len(value) == 0 {
+			buf.WriteString(pad + "{}\n")
+			return
+		}
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			buf.WriteString(pad + yamlKey(key) + ":")
+			writeYAMLMember(buf, value[key], indent)
+		}
+	case []any:
+		if len(value) == 0 {
+			buf.WriteString(pad + "[]\n")
+			return
+		}
+
+		for _, item := range value {
+			// the first member of an object item goes on the line of its dash
+			if m, ok := item.(map[string]any); ok && len(m) != 0 {
+				var block bytes.Buffer
+				writeYAML(&block, m, indent+2)
+				buf.WriteString(pad + "- " + strings.TrimPrefix(block.String(), pad+"  "))
+				continue
+			}
+
+			buf.WriteString(pad + "-")
+			writeYAMLMember(buf, item, indent)
+		}
+	default:
+		buf.WriteString(pad + yamlScalar(value) + "\n")
+	}
+}
+
+// writeYAMLMember writes the value of a key or list item after its marker: scalars and empty collections on
+// the same line, the others in a block indented under it
+func writeYAMLMember(buf *bytes.Buffer, value any, indent int) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+	case []any:
+		if len(v) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+	default:
+		buf.WriteString(" " + yamlScalar(value) + "\n")
+		return
+	}
+
+	buf.WriteString("\n")
+	writeYAML(buf, value, indent+2)
+}
+
+func yamlKey(key string) string {
+	if yamlPlainKeyRx.MatchString(key) {
+		return key
+	}
+
+	return strconv.Quote(key)
+}
+
+func yamlScalar(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		js, _ := json.Marshal(value)
+		return string(js)
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,191 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"movie-api/internal/validators"
+	"time"
+)
+
+const (
+	RoleDirector = "director"
+	RoleWriter   = "writer"
+	RoleCast     = "cast"
+)
+
+var ErrDuplicateCredit = errors.New("duplicate credit")
+
+type Credit struct {
+	ID         int64  `json:"id"`
+	MovieID    int64  `json:"movie_id"`
+	PersonID   int64  `json:"person_id"`
+	PersonName string `json:"person_name,omitempty"`
+	Role       string `json:"role"`
+	Character  string `json:"character,omitempty"`
+}
+
+// FilmographyEntry is a movie a person took part in together with the credit they got
+type FilmographyEntry struct {
+	Role      string  `json:"role"`
+	Character string  `json:"character,omitempty"`
+	Movie     *Movies `json:"movie"`
+}
+
+func ValidateCredit(v *validators.Validators, c *Credit) {
+	v.Check(c.PersonID > 0, "person_id", validators.CodeRequired, "must be provided")
+	v.Check(validators.PermittedValues(c.Role, RoleDirector, RoleWriter, RoleCast), "role", validators.CodeInvalid, "must be director, writer or cast")
+	v.Check(len(c.Character) <= 500, "character", validators.CodeTooLong, "must not be more than 500 bytes long")
+
+	if c.Role != RoleCast {
+		v.Check(c.Character == "", "character", validators.CodeInvalid, "can only be set for cast credits")
+	}
+}
+
+type CreditModel struct {
+	DB *sql.DB
+}
+
+func (m CreditModel) Insert(c *Credit) error {
+	query := `INSERT INTO movie_credits (movie_id, person_id, role, character) VALUES ($1, $2, $3, $4) RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, c.MovieID, c.PersonID, c.Role, c.Character).Scan(&c.ID)
+	if err != nil {
+		switch {
+		case violates(err, pgUniqueViolation, "movie_credits_movie_id_person_id_role_character_key"):
+			return ErrDuplicateCredit
+		case violates(err, pgForeignKeyViolation, "movie_credits_movie_id_fkey"), violates(err, pgForeignKeyViolation, "movie_credits_person_id_fkey"):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CreditModel) Delete(movieID, id int64) error {
+	query := `DELETE FROM movie_credits WHERE movie_id = $1 AND id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetForMovie returns the credits of a movie, directors and writers first and the cast after them
+func (m CreditModel) GetForMovie(movieID int64) ([]*Credit, error) {
+	credits, err := m.GetForMovies([]int64{movieID})
+	if err != nil {
+		return nil, err
+	}
+
+	if credits[movieID] == nil {
+		return []*Credit{}, nil
+	}
+
+	return credits[movieID], nil
+}
+
+// GetForMovies returns the credits of several movies at once keyed by movie id, in the order of GetForMovie
+func (m CreditModel) GetForMovies(movieIDs []int64) (map[int64][]*Credit, error) {
+	query := `
+			SELECT movie_credits.id, movie_credits.movie_id, movie_credits.person_id, people.name, movie_credits.role, movie_credits.character
+			FROM movie_credits
+			INNER JOIN people ON people.id = movie_credits.person_id
+			WHERE movie_credits.movie_id = ANY($1)
+			ORDER BY array_position(ARRAY['director', 'writer', 'cast'], movie_credits.role), movie_credits.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := make(map[int64][]*Credit)
+
+	for rows.Next() {
+		var c Credit
+
+		err := rows.Scan(&c.ID, &c.MovieID, &c.PersonID, &c.PersonName, &c.Role, &c.Character)
+		if err != nil {
+			return nil, err
+		}
+
+		credits[c.MovieID] = append(credits[c.MovieID], &c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+func (m CreditModel) GetFilmography(personID int64, filters Filters) ([]*FilmographyEntry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), movie_credits.role, movie_credits.character,
+			movies.id, movies.created_at, movies.title, movies.year, movies.runtime, movies.genres,
+			movies.average_rating, movies.rating_count, movies.version
+		FROM movie_credits
+		INNER JOIN movies ON movies.id = movie_credits.movie_id
+		WHERE movie_credits.person_id = $1
+		ORDER BY %s, movie_credits.id ASC
+		LIMIT $2 OFFSET $3`, filters.orderBy("movies."))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, personID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	var entries []*FilmographyEntry
+
+	for rows.Next() {
+		var movie Movies
+		entry := FilmographyEntry{Movie: &movie}
+
+		args := []any{&totalRecords, &entry.Role, &entry.Character, &movie.ID, &movie.CreatedAt, &movie.Title, &movie.Year,
+			&movie.Runtime, pq.Array(&movie.Genres), &movie.AverageRating, &movie.RatingCount, &movie.Version}
+		err := rows.Scan(args...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FacetGenres = "genres"
+	FacetYear   = "year"
+)
+
+// FacetCount is the number of movies sharing a value. Year facets bucket movies by decade and carry the
+// bounds of the bucket, which are what the year_min and year_max filters take.
+type FacetCount struct {
+	Value string `json:"value"`
+	Min   int32  `json:"min,omitempty"`
+	Max   int32  `json:"max,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Facets holds the counts of each requested facet, most common values first
+type Facets map[string][]FacetCount
+
+// facetQueries are the branches of the facets query, each reading the filtered movies
+var facetQueries = map[string]string{
+	FacetGenres: `SELECT 'genres', genre, 0, 0, count(*) FROM filtered, unnest(genres) AS genre GROUP BY genre`,
+	FacetYear:   `SELECT 'year', (year / 10 * 10)::text || 's', year / 10 * 10, year / 10 * 10 + 9, count(*) FROM filtered GROUP BY year / 10`,
+}
+
+// GetMovieFacets counts the movies matching the query by each of the facets, all of them in a single query
+func (mm MovieModel) GetMovieFacets(q MovieQuery, facets []string) (Facets, error) {
+	where := q.where()
+
+	var branches []string
+	for _, facet := range facets {
+		branch, ok := facetQueries[facet]
+		if !ok {
+			panic("unknown facet: " + facet)
+		}
+
+		branches = append(branches, branch)
+	}
+
+	query := fmt.Sprintf(`
+		WITH filtered AS (SELECT genres, year FROM movies WHERE %s)
+		%s
+		ORDER BY 1, 5 DESC, 2`, where.String(), strings.Join(branches, " UNION ALL "))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := mm.DB.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(Facets, len(facets))
+	for _, facet := range facets {
+		result[facet] = []FacetCount{}
+	}
+
+	for rows.Next() {
+		var facet string
+		var count FacetCount
+
+		err := rows.Scan(&facet, &count.Value, &count.Min, &count.Max, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		result[facet] = append(result[facet], count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (mmm MovieMockModel) GetMovieFacets(q MovieQuery, facets []string) (Facets, error) {
+	genres := make(map[string]int)
+	decades := make(map[int32]int)
+
+	for _, movie := range mmm.find(q) {
+		for _, genre := range movie.Genres {
+			genres[genre]++
+		}
+		decades[movie.Year/10*10]++
+	}
+
+	result := make(Facets, len(facets))
+
+	for _, facet := range facets {
+		counts := []FacetCount{}
+
+		switch facet {
+		case FacetGenres:
+			for genre, count := range genres {
+				counts = append(counts, FacetCount{Value: genre, Count: count})
+			}
+		case FacetYear:
+			for decade, count := range decades {
+				counts = append(counts, FacetCount{Value: strconv.Itoa(int(decade)) + "s", Min: decade, Max: decade + 9, Count: count})
+			}
+		default:
+			panic("unknown facet: " + facet)
+		}
+
+		slices.SortFunc(counts, func(a, b FacetCount) int {
+			return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value))
+		})
+
+		result[facet] = counts
+	}
+
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/lib/pq"
+	"movie-api/internal/validators"
+	"slices"
+	"strings"
+)
+
+// MovieFields are the fields a movie response can be trimmed to with fields=, in the order they're written
+var MovieFields = []string{"id", "title", "year", "runtime", "genres", "average_rating", "rating_count", "version"}
+
+func ValidateMovieFields(v *validators.Validators, fields []string) {
+	for _, field := range fields {
+		v.Check(validators.PermittedValues(field, MovieFields...), "fields", validators.CodeUnknownValue, "unknown field "+field)
+	}
+
+	v.Check(validators.Unique(fields), "fields", validators.CodeDuplicate, "fields values cannot be duplicated")
+}
+
+// movieSelect returns the column list and scan destinations reading the given fields into m, every column
+// when there are none. The columns are also the names of the fields.
+func movieSelect(m *Movies, fields []string) (string, []any) {
+	if len(fields) == 0 {
+		fields = append([]string{"created_at"}, MovieFields...)
+	}
+
+	dest := make([]any, len(fields))
+
+	for i, field := range fields {
+		switch field {
+		case "id":
+			dest[i] = &m.ID
+		case "created_at":
+			dest[i] = &m.CreatedAt
+		case "title":
+			dest[i] = &m.Title
+		case "year":
+			dest[i] = &m.Year
+		case "runtime":
+			dest[i] = &m.Runtime
+		case "genres":
+			dest[i] = pq.Array(&m.Genres)
+		case "average_rating":
+			dest[i] = &m.AverageRating
+		case "rating_count":
+			dest[i] = &m.RatingCount
+		case "version":
+			dest[i] = &m.Version
+		default:
+			panic("unknown movie field: " + field)
+		}
+	}
+
+	return strings.Join(fields, ", "), dest
+}
+
+// withFields adds the extra fields missing from fields, it leaves an empty list alone since that means all of them
+func withFields(fields []string, extra ...string) []string {
+	if len(fields) == 0 {
+		return fields
+	}
+
+	fields = slices.Clip(fields)
+	for _, field := range extra {
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// MarshalJSON writes every field of the movie, or only the ones it was read with when a fieldset was asked
+// for, the runtime in the format set on the movie. Embedded relations and search results are written
+// whenever they're set.
+func (m Movies) MarshalJSON() ([]byte, error) {
+	type plain Movies
+
+	if len(m.fields) == 0 && m.runtimeFormat == "" {
+		return json.Marshal(plain(m))
+	}
+
+	if len(m.fields) == 0 {
+		var runtime string
+		if m.Runtime != 0 {
+			runtime = m.Runtime.Format(m.runtimeFormat)
+		}
+
+		// the outer runtime field shadows the one of the movie
+		return json.Marshal(struct {
+			plain
+			Runtime string `json:"runtime,omitempty"`
+		}{plain(m), runtime})
+	}
+
+	var buf bytes.Buffer
+
+	write := func(key string, value any) error {
+		js, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		if buf.Len() != 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(`"` + key + `":`)
+		buf.Write(js)
+		return nil
+	}
+
+	values := map[string]any{
+		"id":             m.ID,
+		"title":          m.Title,
+		"year":           m.Year,
+		"runtime":        m.Runtime.Format(m.runtimeFormat),
+		"genres":         m.Genres,
+		"average_rating": m.AverageRating,
+		"rating_count":   m.RatingCount,
+		"version":        m.Version,
+	}
+
+	for _, field := range m.fields {
+		err := write(field, values[field])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	extras := []struct {
+		key   string
+		value any
+		set   bool
+	}{
+		{"credits", m.Credits, m.Credits != nil},
+		{"score", m.Score, m.Score != 0},
+		{"highlight", m.Highlight, m.Highlight != ""},
+	}
+
+	for _, extra := range extras {
+		if !extra.set {
+			continue
+		}
+
+		err := write(extra.key, extra.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []byte("{" + buf.String() + "}"), nil
+}
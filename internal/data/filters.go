@@ -1,42 +1,196 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"movie-api/internal/validators"
 	"strings"
 )
 
 type Filters struct {
-	Page     int
-	PageSize int
-	Sort     string
-	SortList []string
+	Page      int
+	PageSize  int
+	Sort      string
+	SortList  []string
+	Cursor    string
+	SkipTotal bool
 }
 
 func ValidateFilters(v *validators.Validators, f Filters) {
-	v.Check(f.Page > 0, "page", "must be greater than 0")
-	v.Check(f.Page <= 10_000_000, "page", "must be lower than 10 million")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than 0")
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
-	v.Check(validators.PermittedValues(f.Sort, f.SortList...), "sort_list", "invalid sort value")
+	v.Check(f.Page > 0, "page", validators.CodeOutOfRange, "must be greater than 0")
+	v.Check(f.Page <= 10_000_000, "page", validators.CodeOutOfRange, "must be lower than 10 million")
+	v.Check(f.PageSize > 0, "page_size", validators.CodeOutOfRange, "must be greater than 0")
+	v.Check(f.PageSize <= 100, "page_size", validators.CodeOutOfRange, "must be a maximum of 100")
+	sortValid := validateSort(v, f)
+
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+
+		// a cursor of the same sort must still hold a value of the right type for every order key
+		if err == nil && c.Sort == f.Sort && sortValid {
+			_, err = c.values(f.orderKeys())
+		}
+
+		v.Check(err == nil, "cursor", validators.CodeInvalid, "must be a cursor returned by a previous request")
+		v.Check(err != nil || c.Sort == f.Sort, "cursor", validators.CodeInvalid, "was issued for a different sort value")
+		v.Check(f.Page == 1, "page", validators.CodeConflict, "cannot be combined with a cursor")
+	}
 }
 
-func (f Filters) sortColumn() string {
-	for _, value := range f.SortList {
-		if f.Sort == value {
-			return strings.TrimPrefix(f.Sort, "-")
+// validateSort checks every key of a comma separated sort value against the sort list, reporting each bad key
+func validateSort(v *validators.Validators, f Filters) bool {
+	var invalid []string
+	seen := make(map[string]bool)
+
+	// a key outside the sort list weighs more than a column sorted on twice
+	code := validators.CodeDuplicate
+
+	for _, key := range strings.Split(f.Sort, ",") {
+		column := strings.TrimPrefix(key, "-")
+
+		switch {
+		case !validators.PermittedValues(key, f.SortList...):
+			invalid = append(invalid, fmt.Sprintf("%q is not a valid sort value", key))
+			code = validators.CodeNotPermitted
+		case seen[column]:
+			invalid = append(invalid, fmt.Sprintf("%q sorts by %s more than once", key, column))
 		}
+
+		seen[column] = true
+	}
+
+	v.Check(len(invalid) == 0, "sort", code, strings.Join(invalid, "; "))
+
+	return len(invalid) == 0
+}
+
+// cursor is the position of a row in a keyset paginated list: the values of the order keys, the id breaking
+// ties included. Backward cursors list the rows before that position instead of the ones after it.
+type cursor struct {
+	Sort     string            `json:"s"`
+	Values   []json.RawMessage `json:"v"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	js, err := json.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// values decodes the values of the cursor for the order keys they were taken from
+func (c cursor) values(keys []sortKey) ([]any, error) {
+	if len(c.Values) != len(keys) {
+		return nil, errors.New("cursor does not match the sort keys")
+	}
+
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		var err error
+
+		values[i], err = movieCursorValue(key.column, c.Values[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
 	}
 
-	panic("unsafe sort parameter:" + f.Sort)
+	err = json.Unmarshal(js, &c)
+	return c, err
 }
 
-func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC"
+// sortColumns maps the sort values that don't match a column name to the column they order by
+var sortColumns = map[string]string{
+	"rating": "average_rating",
+}
+
+// sortKey is a column of an ORDER BY clause with its direction
+type sortKey struct {
+	column    string
+	direction string
+}
+
+// sortKeys turns the sort value into the columns to order by. Every key is matched against the sort list
+// again, so nothing that wasn't validated can reach the query.
+func (f Filters) sortKeys() []sortKey {
+	var keys []sortKey
+
+	for _, key := range strings.Split(f.Sort, ",") {
+		if !validators.PermittedValues(key, f.SortList...) {
+			panic("unsafe sort parameter:" + key)
+		}
+
+		column := strings.TrimPrefix(key, "-")
+		if mapped, ok := sortColumns[column]; ok {
+			column = mapped
+		}
+
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			direction = "DESC"
+		}
+
+		keys = append(keys, sortKey{column: column, direction: direction})
+	}
+
+	return keys
+}
+
+// orderKeys returns the sort keys followed by the id when it isn't one of them, so that rows always come in
+// the same order. The id follows the direction of the last key.
+func (f Filters) orderKeys() []sortKey {
+	keys := f.sortKeys()
+
+	for _, key := range keys {
+		if key.column == "id" {
+			return keys
+		}
+	}
+
+	return append(keys, sortKey{column: "id", direction: keys[len(keys)-1].direction})
+}
+
+// orderBy builds the ORDER BY list of the filters, table prefixes every column when it's not empty
+func (f Filters) orderBy(table string) string {
+	return joinSortKeys(table, f.orderKeys())
+}
+
+func joinSortKeys(table string, keys []sortKey) string {
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = table + key.column + " " + key.direction
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+// reverseSortKeys flips the direction of every key, which walks a keyset backward
+func reverseSortKeys(keys []sortKey) []sortKey {
+	reversed := make([]sortKey, len(keys))
+	for i, key := range keys {
+		reversed[i] = sortKey{column: key.column, direction: "DESC"}
+		if key.direction == "DESC" {
+			reversed[i].direction = "ASC"
+		}
 	}
 
-	return "ASC"
+	return reversed
 }
 
 func (f Filters) limit() int {
@@ -48,11 +202,13 @@ func (f Filters) offset() int {
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:",omitempty,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:",omitempty,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"encoding/json"
+	"movie-api/internal/validators"
+	"slices"
+	"testing"
+)
+
+var movieSortList = []string{"id", "title", "year", "-id", "-title", "-year"}
+
+func TestValidateFiltersCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		sort   string
+		cursor cursor
+		valid  bool
+	}{
+		{"matching", "title", cursor{Sort: "title", Values: raw(`"Heat"`, `3`)}, true},
+		{"too few values", "title", cursor{Sort: "title", Values: raw(`"Heat"`)}, false},
+		{"too many values", "id", cursor{Sort: "id", Values: raw(`3`, `4`)}, false},
+		{"wrong type", "year", cursor{Sort: "year", Values: raw(`"1995"`, `3`)}, false},
+		{"fractional id", "id", cursor{Sort: "id", Values: raw(`3.5`)}, false},
+		{"other sort", "year", cursor{Sort: "title", Values: raw(`"Heat"`, `3`)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validators.New()
+			ValidateFilters(v, Filters{Page: 1, PageSize: 10, Sort: tt.sort, SortList: movieSortList, Cursor: encodeCursor(tt.cursor)})
+
+			if _, invalid := v.Errors["cursor"]; invalid == tt.valid {
+				t.Errorf("cursor errors: %v, want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+
+	v := validators.New()
+	ValidateFilters(v, Filters{Page: 1, PageSize: 10, Sort: "id", SortList: movieSortList, Cursor: "not a cursor!"})
+
+	if v.Errors["cursor"] == "" {
+		t.Errorf("undecodable cursor: got no cursor error")
+	}
+}
+
+func TestMovieMockModelCursor(t *testing.T) {
+	var movies []*Movies
+	for i := int64(1); i <= 7; i++ {
+		movies = append(movies, &Movies{ID: i, Title: "Movie", Year: 2000 + int32(i%3)})
+	}
+
+	model := MovieMockModel{Movies: movies}
+	filters := Filters{Page: 1, PageSize: 3, Sort: "year", SortList: movieSortList, SkipTotal: true}
+
+	var forward [][]int64
+	var cursors []string
+
+	for {
+		page, metadata, err := model.GetAllMovies(MovieQuery{}, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if metadata.TotalRecords != 0 {
+			t.Errorf("total records: got %d with SkipTotal", metadata.TotalRecords)
+		}
+
+		forward = append(forward, ids(page))
+		cursors = append(cursors, metadata.PrevCursor)
+
+		if metadata.NextCursor == "" {
+			break
+		}
+
+		filters.Cursor = metadata.NextCursor
+	}
+
+	// by year then id: 3 6 | 1 4 7 | 2 5
+	want := [][]int64{{3, 6, 1}, {4, 7, 2}, {5}}
+	if !slices.EqualFunc(forward, want, slices.Equal) {
+		t.Fatalf("forward pages: got %v, want %v", forward, want)
+	}
+
+	filters.Cursor = cursors[len(cursors)-1]
+
+	page, metadata, err := model.GetAllMovies(MovieQuery{}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ids(page); !slices.Equal(got, want[1]) {
+		t.Errorf("backward page: got %v, want %v", got, want[1])
+	}
+
+	if metadata.PrevCursor == "" || metadata.NextCursor == "" {
+		t.Errorf("backward page: got cursors %q and %q, want both", metadata.PrevCursor, metadata.NextCursor)
+	}
+}
+
+func raw(values ...string) []json.RawMessage {
+	var out []json.RawMessage
+	for _, value := range values {
+		out = append(out, json.RawMessage(value))
+	}
+
+	return out
+}
+
+func ids(movies []*Movies) []int64 {
+	var out []int64
+	for _, movie := range movies {
+		out = append(out, movie.ID)
+	}
+
+	return out
+}
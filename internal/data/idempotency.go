@@ -0,0 +1,131 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyKeyInFlight = errors.New("idempotency key in flight")
+)
+
+// StoredResponse is the first response given to a request carrying an idempotency key
+type StoredResponse struct {
+	Status  int
+	Headers http.Header
+	Body    []byte
+}
+
+type IdempotencyModel struct {
+	DB *sql.DB
+}
+
+// Claim reserves the key of a user for a request. It returns nil when the request is the first one with the key,
+// or an expired key got claimed again, and the caller must run it. A retry gets the stored response back,
+// unless the first request is still running or the request differs.
+//
+// The claim holds the key for lease only, Complete extends it to the replay ttl. A request that dies without
+// completing or releasing its key, in a crash say, leaves a claim a retry takes over once the lease runs out.
+func (m IdempotencyModel) Claim(userID int64, key string, hash []byte, lease time.Duration) (*StoredResponse, error) {
+	// the row acts as the lock of the key, a conflicting insert only takes over an expired claim or response
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, status = NULL, headers = NULL, body = NULL,
+			created_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()
+		RETURNING user_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var claimed int64
+
+	err := m.DB.QueryRowContext(ctx, query, userID, key, hash, time.Now().Add(lease)).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	query = `SELECT request_hash, status, headers, body FROM idempotency_keys WHERE user_id = $1 AND key = $2`
+
+	var storedHash, headers, body []byte
+	var status sql.NullInt32
+
+	// the key can expire and vanish in between, the retry will claim it
+	err = m.DB.QueryRowContext(ctx, query, userID, key).Scan(&storedHash, &status, &headers, &body)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrIdempotencyKeyInFlight
+		default:
+			return nil, err
+		}
+	}
+
+	switch {
+	case string(storedHash) != string(hash):
+		return nil, ErrIdempotencyKeyReused
+	case !status.Valid:
+		return nil, ErrIdempotencyKeyInFlight
+	}
+
+	response := &StoredResponse{Status: int(status.Int32), Body: body}
+
+	err = json.Unmarshal(headers, &response.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Complete stores the response of the request that claimed the key, retries get it from now on until ttl
+// has passed
+func (m IdempotencyModel) Complete(userID int64, key string, response *StoredResponse, ttl time.Duration) error {
+	headers, err := json.Marshal(response.Headers)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE idempotency_keys SET status = $1, headers = $2, body = $3, expires_at = $4
+		WHERE user_id = $5 AND key = $6 AND status IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, response.Status, string(headers), response.Body, time.Now().Add(ttl), userID, key)
+	return err
+}
+
+// Release gives up a claimed key without a response, so the request can be retried with it
+func (m IdempotencyModel) Release(userID int64, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, key)
+	return err
+}
+
+// DeleteExpired removes the keys that can't be replayed anymore
+func (m IdempotencyModel) DeleteExpired() error {
+	query := `DELETE FROM idempotency_keys WHERE expires_at < NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query)
+	return err
+}
@@ -0,0 +1,258 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"time"
+)
+
+const (
+	ImportInsert = "insert"
+	ImportUpsert = "upsert"
+
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// importBatchSize is the amount of rows copied and committed per transaction
+const importBatchSize = 1000
+
+// ImportRowError holds the validation errors of a single row of an import file, rows start at 1
+type ImportRowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+type ImportJob struct {
+	ID         int64            `json:"id"`
+	UserID     int64            `json:"-"`
+	CreatedAt  time.Time        `json:"created_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Mode       string           `json:"mode"`
+	DryRun     bool             `json:"dry_run"`
+	Status     string           `json:"status"`
+	TotalRows  int              `json:"total_rows"`
+	ValidRows  int              `json:"valid_rows"`
+	Inserted   int              `json:"inserted"`
+	Updated    int              `json:"updated"`
+	RowErrors  []ImportRowError `json:"row_errors"`
+	Failure    string           `json:"failure,omitempty"`
+}
+
+type ImportModel struct {
+	DB *sql.DB
+}
+
+func (m ImportModel) Insert(job *ImportJob) error {
+	rowErrors, err := json.Marshal(job.RowErrors)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO import_jobs (user_id, finished_at, mode, dry_run, status, total_rows, valid_rows, row_errors)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`
+
+	args := []any{job.UserID, job.FinishedAt, job.Mode, job.DryRun, job.Status, job.TotalRows, job.ValidRows, string(rowErrors)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&job.ID, &job.CreatedAt)
+}
+
+func (m ImportModel) Get(id int64) (*ImportJob, error) {
+	query := `SELECT id, COALESCE(user_id, 0), created_at, finished_at, mode, dry_run, status, total_rows, valid_rows, inserted, updated, row_errors, failure
+			FROM import_jobs WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var job ImportJob
+	var rowErrors []byte
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.CreatedAt,
+		&job.FinishedAt,
+		&job.Mode,
+		&job.DryRun,
+		&job.Status,
+		&job.TotalRows,
+		&job.ValidRows,
+		&job.Inserted,
+		&job.Updated,
+		&rowErrors,
+		&job.Failure,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = json.Unmarshal(rowErrors, &job.RowErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// UpdateProgress stores the status and counters of a job, row errors are only written on insert
+func (m ImportModel) UpdateProgress(job *ImportJob) error {
+	query := `UPDATE import_jobs SET status = $1, inserted = $2, updated = $3, failure = $4, finished_at = $5 WHERE id = $6`
+
+	args := []any{job.Status, job.Inserted, job.Updated, job.Failure, job.FinishedAt, job.ID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Run copies the movies in batches and records an insert or update revision for each of them.
+// Every batch commits on its own, the counters of the job reflect the batches committed before a failure.
+func (m ImportModel) Run(job *ImportJob, movies []*Movies) error {
+	for start := 0; start < len(movies); start += importBatchSize {
+		end := min(start+importBatchSize, len(movies))
+
+		inserted, updated, err := m.runBatch(job, movies[start:end])
+		if err != nil {
+			return err
+		}
+
+		job.Inserted += inserted
+		job.Updated += updated
+
+		err = m.UpdateProgress(job)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ImportModel) runBatch(job *ImportJob, movies []*Movies) (int, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows := make([][]any, len(movies))
+	for i, movie := range movies {
+		rows[i] = []any{i, movie.Title, movie.Year, int32(movie.Runtime), movie.Genres}
+	}
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	var inserted, updated int64
+
+	// COPY is only reachable through the pgx connection underneath database/sql
+	err = conn.Raw(func(driverConn any) error {
+		tx, err := driverConn.(*stdlib.Conn).Conn().Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, `CREATE TEMPORARY TABLE movie_import (seq integer, title text, year integer, runtime integer, genres text[]) ON COMMIT DROP`)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"movie_import"}, []string{"seq", "title", "year", "runtime", "genres"}, pgx.CopyFromRows(rows))
+		if err != nil {
+			return err
+		}
+
+		if job.Mode == ImportUpsert {
+			_, err = tx.Exec(ctx, importDedupeQuery)
+			if err != nil {
+				return err
+			}
+
+			tag, err := tx.Exec(ctx, importUpdateQuery, job.UserID)
+			if err != nil {
+				return err
+			}
+			updated = tag.RowsAffected()
+		}
+
+		tag, err := tx.Exec(ctx, importInsertQuery, job.UserID, job.Mode == ImportUpsert)
+		if err != nil {
+			return err
+		}
+		inserted = tag.RowsAffected()
+
+		return tx.Commit(ctx)
+	})
+
+	return int(inserted), int(updated), err
+}
+
+// importDedupeQuery keeps the last staged row of each title and year, so an upsert writes every movie once
+const importDedupeQuery = `
+	DELETE FROM movie_import s
+	USING movie_import later
+	WHERE later.title = s.title AND later.year = s.year AND later.seq > s.seq`
+
+// importInsertQuery inserts the staged movies, skipping the ones matching an existing title and year when $2 is true.
+// The revisions mirror what newRevision records for a single insert.
+const importInsertQuery = `
+	WITH inserted AS (
+		INSERT INTO movies (title, year, runtime, genres)
+		SELECT s.title, s.year, s.runtime, s.genres FROM movie_import s
+		WHERE $2 = false OR NOT EXISTS (SELECT 1 FROM movies m WHERE m.title = s.title AND m.year = s.year)
+		RETURNING id, title, year, runtime, genres, version
+	)
+	INSERT INTO movie_revisions (movie_id, version, action, actor_id, changes, snapshot)
+	SELECT id, version, 'insert', $1,
+		jsonb_build_object(
+			'title', jsonb_build_object('old', NULL, 'new', title),
+			'year', jsonb_build_object('old', NULL, 'new', year),
+			'runtime', jsonb_build_object('old', NULL, 'new', runtime::text || ' mins'),
+			'genres', jsonb_build_object('old', NULL, 'new', to_jsonb(genres))
+		),
+		jsonb_build_object('title', title, 'year', year, 'runtime', runtime::text || ' mins', 'genres', to_jsonb(genres))
+	FROM inserted`
+
+// importUpdateQuery updates the runtime and genres of the movies matching a staged title and year
+const importUpdateQuery = `
+	WITH previous AS (
+		SELECT m.id, m.runtime, m.genres
+		FROM movies m
+		INNER JOIN movie_import s ON m.title = s.title AND m.year = s.year
+		WHERE m.runtime <> s.runtime OR m.genres <> s.genres
+		FOR UPDATE OF m
+	), updated AS (
+		UPDATE movies m
+		SET runtime = s.runtime, genres = s.genres, version = m.version + 1
+		FROM movie_import s, previous
+		WHERE previous.id = m.id AND m.title = s.title AND m.year = s.year
+		RETURNING m.id, m.title, m.year, m.runtime, m.genres, m.version, previous.runtime AS old_runtime, previous.genres AS old_genres
+	)
+	INSERT INTO movie_revisions (movie_id, version, action, actor_id, changes, snapshot)
+	SELECT id, version, 'update', $1,
+		CASE WHEN old_runtime <> runtime
+			THEN jsonb_build_object('runtime', jsonb_build_object('old', old_runtime::text || ' mins', 'new', runtime::text || ' mins'))
+			ELSE '{}'::jsonb END
+		|| CASE WHEN old_genres <> genres
+			THEN jsonb_build_object('genres', jsonb_build_object('old', to_jsonb(old_genres), 'new', to_jsonb(genres)))
+			ELSE '{}'::jsonb END,
+		jsonb_build_object('title', title, 'year', year, 'runtime', runtime::text || ' mins', 'genres', to_jsonb(genres))
+	FROM updated`
@@ -1,17 +1,40 @@
 package data
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
 type Models struct {
 	Movies interface {
-		InsertMovie(m *Movies) error
-		GetMovie(id int64) (*Movies, error)
-		UpdateMovie(movie *Movies) (*Movies, error)
-		GetAllMovies(title string, genres []string, filters Filters) ([]*Movies, Metadata, error)
+		InsertMovie(m *Movies, actorID int64) error
+		GetMovie(id int64, fields ...string) (*Movies, error)
+		UpdateMovie(movie *Movies, actorID int64) (*Movies, error)
+		DeleteMovie(id int64, version int32, actorID int64) error
+		GetAllMovies(q MovieQuery, filters Filters) ([]*Movies, Metadata, error)
+		GetMovieFacets(q MovieQuery, facets []string) (Facets, error)
+		ExportMovies(ctx context.Context, q MovieQuery, fn func(*Movies) error) error
 	}
-	Tokens      TokenModel
-	Users       UserModel
-	Permissions PermissionModel
+	Tokens TokenModel
+	Users  interface {
+		InsertUser(user *User) error
+		GetUserByEmail(email string) (*User, error)
+		UpdateUser(user *User) error
+		GetForToken(tokenScope, tokenPlaintext string) (*User, error)
+	}
+	Permissions interface {
+		GetAllForUser(userID int64) (Permissions, error)
+		AddForUser(userID int64, codes ...string) error
+		GetAll() (Permissions, error)
+		RemoveForUser(userID int64, codes ...string) (int64, error)
+	}
+	Preferences PreferenceModel
+	Revisions   RevisionModel
+	Reviews     ReviewModel
+	People      PersonModel
+	Credits     CreditModel
+	Imports     ImportModel
+	Idempotency IdempotencyModel
 }
 
 func NewModels(db *sql.DB) Models {
@@ -20,12 +43,20 @@ func NewModels(db *sql.DB) Models {
 		Tokens:      TokenModel{DB: db},
 		Users:       UserModel{DB: db},
 		Permissions: PermissionModel{DB: db},
+		Preferences: PreferenceModel{DB: db},
+		Revisions:   RevisionModel{DB: db},
+		Reviews:     ReviewModel{DB: db},
+		People:      PersonModel{DB: db},
+		Credits:     CreditModel{DB: db},
+		Imports:     ImportModel{DB: db},
+		Idempotency: IdempotencyModel{DB: db},
 	}
 }
 
-func NewMovieMockModel() Models {
+func NewMovieMockModel(movies ...*Movies) Models {
 	return Models{
-		Movies: MovieMockModel{},
-		Users:  UserModel{},
+		Movies:      MovieMockModel{Movies: movies},
+		Users:       UserMockModel{},
+		Permissions: PermissionMockModel{},
 	}
 }
@@ -1,12 +1,15 @@
 package data
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
 	"movie-api/internal/validators"
+	"slices"
 	"time"
 )
 
@@ -15,64 +18,236 @@ type MovieModel struct {
 }
 
 type Movies struct {
-	ID        int64     `json:"id,omitempty"`
-	CreatedAt time.Time `json:"-"`
-	Title     string    `json:"title,omitempty"`
-	Year      int32     `json:"year,omitempty"`
-	Runtime   Runtime   `json:"runtime,omitempty"`
-	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"version,omitempty"`
+	ID            int64     `json:"id,omitempty"`
+	CreatedAt     time.Time `json:"-"`
+	Title         string    `json:"title,omitempty"`
+	Year          int32     `json:"year,omitempty"`
+	Runtime       Runtime   `json:"runtime,omitempty"`
+	Genres        []string  `json:"genres,omitempty"`
+	AverageRating float64   `json:"average_rating"`
+	RatingCount   int32     `json:"rating_count"`
+	Version       int32     `json:"version,omitempty"`
+	Credits       []*Credit `json:"credits,omitempty"`
+	Score         float64   `json:"score,omitempty"`
+	Highlight     string    `json:"highlight,omitempty"`
+
+	// fields are the fields the movie was read with when it's trimmed to a fieldset
+	fields []string
+
+	// runtimeFormat is the format the runtime is written in, minutes when empty
+	runtimeFormat string
+}
+
+// SetRuntimeFormat picks the format the runtime of the movie is written in, one of the runtime formats
+func (m *Movies) SetRuntimeFormat(format string) {
+	m.runtimeFormat = format
 }
 
 func CheckValidators(v *validators.Validators, m *Movies) {
-	v.Check(m.Title != "", "title", "title must be provided")
-	v.Check(m.Year != 0, "year", "year field must be provided")
-	v.Check(m.Year >= 1990, "year", "year value must be from 1990 to actual year")
-	v.Check(m.Runtime >= 20, "runtime", "runtime value must be equal or greater 20 minutes")
-	v.Check(m.Runtime != 0, "runtime", "runtime field must be provided")
-	v.Check(len(m.Genres) != 0, "genres", "at least one genre must be provided")
-	v.Check(validators.Unique(m.Genres), "genres", "genres values cannot be duplicated")
+	v.Check(m.Title != "", "title", validators.CodeRequired, "title must be provided")
+	v.Check(m.Year != 0, "year", validators.CodeRequired, "year field must be provided")
+	v.Check(m.Year >= 1990, "year", validators.CodeInvalid, "year value must be from 1990 to actual year")
+	v.Check(m.Runtime >= 20, "runtime", validators.CodeInvalid, "runtime value must be equal or greater 20 minutes")
+	v.Check(m.Runtime != 0, "runtime", validators.CodeRequired, "runtime field must be provided")
+	v.Check(len(m.Genres) != 0, "genres", validators.CodeRequired, "at least one genre must be provided")
+	v.Check(validators.Unique(m.Genres), "genres", validators.CodeDuplicate, "genres values cannot be duplicated")
+}
+
+// MovieQuery holds the predicates of a movie listing, zero values leave a predicate out
+type MovieQuery struct {
+	Search        string
+	Title         string
+	Genres        []string
+	GenresAny     []string
+	GenresExclude []string
+	YearMin       int32
+	YearMax       int32
+	RuntimeMin    Runtime
+	RuntimeMax    Runtime
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// Fields trims the listed movies to a fieldset, it doesn't filter anything
+	Fields []string
+}
+
+func ValidateMovieQuery(v *validators.Validators, q MovieQuery) {
+	v.Check(len(q.Search) <= 200, "q", validators.CodeTooLong, "must not be more than 200 bytes long")
+	v.Check(q.YearMin >= 0, "year_min", validators.CodeInvalid, "must not be negative")
+	v.Check(q.YearMax >= 0, "year_max", validators.CodeInvalid, "must not be negative")
+	v.Check(q.YearMin == 0 || q.YearMax == 0 || q.YearMin <= q.YearMax, "year_max", validators.CodeOutOfRange, "must be greater than or equal to year_min")
+	v.Check(q.RuntimeMin >= 0, "runtime_min", validators.CodeInvalid, "must not be negative")
+	v.Check(q.RuntimeMax >= 0, "runtime_max", validators.CodeInvalid, "must not be negative")
+	v.Check(q.RuntimeMin == 0 || q.RuntimeMax == 0 || q.RuntimeMin <= q.RuntimeMax, "runtime_max", validators.CodeOutOfRange, "must be greater than or equal to runtime_min")
+	v.Check(q.CreatedAfter.IsZero() || q.CreatedBefore.IsZero() || q.CreatedAfter.Before(q.CreatedBefore), "created_before", validators.CodeOutOfRange, "must be later than created_after")
+	v.Check(validators.Unique(q.Genres), "genres", validators.CodeDuplicate, "genres values cannot be duplicated")
+	v.Check(validators.Unique(q.GenresAny), "genres_any", validators.CodeDuplicate, "genres values cannot be duplicated")
+	v.Check(validators.Unique(q.GenresExclude), "genres_exclude", validators.CodeDuplicate, "genres values cannot be duplicated")
+
+	for _, genre := range q.GenresExclude {
+		v.Check(!validators.PermittedValues(genre, q.Genres...), "genres_exclude", validators.CodeConflict, "cannot exclude a required genre: "+genre)
+		v.Check(!validators.PermittedValues(genre, q.GenresAny...), "genres_exclude", validators.CodeConflict, "cannot exclude a genre of genres_any: "+genre)
+	}
+}
+
+// where builds the conditions of the query, shared by every statement listing movies
+func (q MovieQuery) where() *whereClause {
+	w := &whereClause{}
+
+	if q.Search != "" {
+		q.searchCondition(w)
+	}
+
+	if q.Title != "" {
+		w.add("to_tsvector('simple', title) @@ plainto_tsquery('simple', ?)", q.Title)
+	}
+
+	if len(q.Genres) != 0 {
+		w.add("genres @> ?", pq.Array(q.Genres))
+	}
+
+	if len(q.GenresAny) != 0 {
+		w.add("genres && ?", pq.Array(q.GenresAny))
+	}
+
+	if len(q.GenresExclude) != 0 {
+		w.add("NOT (genres && ?)", pq.Array(q.GenresExclude))
+	}
+
+	if q.YearMin != 0 {
+		w.add("year >= ?", q.YearMin)
+	}
+
+	if q.YearMax != 0 {
+		w.add("year <= ?", q.YearMax)
+	}
+
+	if q.RuntimeMin != 0 {
+		w.add("runtime >= ?", int32(q.RuntimeMin))
+	}
+
+	if q.RuntimeMax != 0 {
+		w.add("runtime <= ?", int32(q.RuntimeMax))
+	}
+
+	if !q.CreatedAfter.IsZero() {
+		w.add("created_at > ?", q.CreatedAfter)
+	}
+
+	if !q.CreatedBefore.IsZero() {
+		w.add("created_at < ?", q.CreatedBefore)
+	}
+
+	return w
 }
 
-func (mm MovieModel) InsertMovie(m *Movies) error {
+func (mm MovieModel) InsertMovie(m *Movies, actorID int64) error {
 	query := `INSERT INTO movies (title, year, runtime, genres) VALUES ($1, $2, $3, $4) RETURNING id, created_at, version`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	return mm.DB.QueryRowContext(ctx, query, m.Title, m.Year, m.Runtime, pq.Array(m.Genres)).Scan(&m.ID, &m.CreatedAt, &m.Version)
+
+	tx, err := mm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, m.Title, m.Year, m.Runtime, pq.Array(m.Genres)).Scan(&m.ID, &m.CreatedAt, &m.Version)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(ctx, tx, newRevision(RevisionInsert, nil, m, actorID))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (mm MovieModel) GetAllMovies(title string, genres []string, filters Filters) ([]*Movies, Metadata, error) {
-	query := fmt.Sprintf(`
-		SELECT 
-		    count(*) OVER(), id, created_at, title, year, runtime, genres, version 
-		FROM 
-		    movies 
-		WHERE 
-		    (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1)
-		   OR $1='')
-		AND
-		    (genres @> $2 OR $2 = '{}')
-		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+// GetAllMovies lists the movies matching the query. Without a cursor it pages with LIMIT/OFFSET,
+// with one it seeks past the position of the order keys the cursor holds. Either way the metadata carries the
+// cursors of the neighbouring pages, and the total count is a separate query clients can opt out of.
+// The filtering happens in a subquery that also computes the search score, so it can be sorted and sought on.
+func (mm MovieModel) GetAllMovies(q MovieQuery, filters Filters) ([]*Movies, Metadata, error) {
+	where := q.where()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	rows, err := mm.DB.QueryContext(ctx, query, title, pq.Array(genres), filters.limit(), filters.offset())
+	totalRecords := 0
+
+	if !filters.SkipTotal {
+		query := `SELECT count(*) FROM movies WHERE ` + where.String()
+
+		err := mm.DB.QueryRowContext(ctx, query, where.args...).Scan(&totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	score, highlight := q.searchColumns(where)
+	filtered := where.nest()
+
+	keys := filters.orderKeys()
+
+	// the cursors are made of the order keys, they're read whatever the fieldset
+	fields := q.Fields
+	for _, key := range keys {
+		if key.column != "score" {
+			fields = withFields(fields, key.column)
+		}
+	}
+
+	var c cursor
+	offset := filters.offset()
+
+	if filters.Cursor != "" {
+		var err error
+
+		c, err = decodeCursor(filters.Cursor)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		values, err := c.values(keys)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		// walking backward flips the order, the rows are put back in place once fetched
+		if c.Backward {
+			keys = reverseSortKeys(keys)
+		}
+
+		where.seek(keys, values)
+		offset = 0
+	}
+
+	// one extra row tells whether there is a page after this one
+	columns, _ := movieSelect(&Movies{}, fields)
+
+	query := fmt.Sprintf(`
+		SELECT %s, score, highlight
+		FROM (SELECT *, %s AS score, %s AS highlight FROM movies WHERE %s) AS movies
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s OFFSET %s`, columns, score, highlight, filtered, where.String(), joinSortKeys("", keys), where.arg(filters.limit()+1), where.arg(offset))
+
+	rows, err := mm.DB.QueryContext(ctx, query, where.args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 	defer rows.Close()
 
-	totalRecords := 0
 	var movies []*Movies
 
 	for rows.Next() {
-		var movie Movies
+		movie := Movies{fields: q.Fields}
 
-		args := []any{&totalRecords, &movie.ID, &movie.CreatedAt, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.Version}
-		err := rows.Scan(args...)
+		_, args := movieSelect(&movie, fields)
+		err := rows.Scan(append(args, &movie.Score, &movie.Highlight)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -84,27 +259,230 @@ func (mm MovieModel) GetAllMovies(title string, genres []string, filters Filters
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	more := len(movies) > filters.limit()
+	if more {
+		movies = movies[:filters.limit()]
+	}
+
+	if c.Backward {
+		slices.Reverse(movies)
+	}
 
-	return movies, metadata, nil
+	return movies, pageMetadata(filters, c, movies, more, totalRecords), nil
 }
 
-func (mm MovieModel) GetMovie(id int64) (*Movies, error) {
-	query := `SELECT id, created_at, title, year, runtime, genres, version FROM movies WHERE id=$1`
+// pageMetadata builds the metadata of a page of movies: page numbers when it was reached without a cursor,
+// and the cursors of the neighbouring pages either way. more tells whether a row followed the page in the
+// direction it was read.
+func pageMetadata(filters Filters, c cursor, movies []*Movies, more bool, totalRecords int) Metadata {
+	metadata := Metadata{PageSize: filters.PageSize, TotalRecords: totalRecords}
+	if filters.Cursor == "" {
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		metadata.CurrentPage = filters.Page
+		metadata.PageSize = filters.PageSize
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	if len(movies) != 0 {
+		first, last := movies[0], movies[len(movies)-1]
 
-	var movie Movies
+		hasNext := more || c.Backward
+		hasPrev := (more && c.Backward) || (!c.Backward && (filters.Cursor != "" || filters.Page > 1))
+
+		if hasNext {
+			metadata.NextCursor = encodeCursor(cursor{Sort: filters.Sort, Values: movieCursorValues(filters, last)})
+		}
+
+		if hasPrev {
+			metadata.PrevCursor = encodeCursor(cursor{Sort: filters.Sort, Values: movieCursorValues(filters, first), Backward: true})
+		}
+	}
+
+	return metadata
+}
+
+// movieCursorValues encodes the values of the order keys of a movie for a cursor
+func movieCursorValues(filters Filters, m *Movies) []json.RawMessage {
+	var values []json.RawMessage
+
+	for _, key := range filters.orderKeys() {
+		values = append(values, movieCursorField(key.column, m))
+	}
+
+	return values
+}
+
+func movieCursorField(column string, m *Movies) json.RawMessage {
+	var value any
+
+	switch column {
+	case "title":
+		value = m.Title
+	case "year":
+		value = m.Year
+	case "runtime":
+		value = int32(m.Runtime)
+	case "average_rating":
+		value = m.AverageRating
+	case "score":
+		value = m.Score
+	default:
+		value = m.ID
+	}
+
+	js, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return js
+}
+
+// movieCursorValue decodes the sort column value of a cursor into the Go type of the column
+func movieCursorValue(column string, raw json.RawMessage) (any, error) {
+	var err error
+
+	switch column {
+	case "title":
+		var value string
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case "year", "runtime":
+		var value int32
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case "average_rating", "score":
+		var value float64
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	default:
+		var value int64
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	}
+}
+
+// exportFetchSize is the amount of rows pulled from the export cursor at a time
+const exportFetchSize = 500
+
+// ExportMovies streams every movie matching the query to fn, ordered by id. Rows are fetched
+// from a server-side cursor in small chunks so memory stays flat whatever the size of the table.
+func (mm MovieModel) ExportMovies(ctx context.Context, q MovieQuery, fn func(*Movies) error) error {
+	tx, err := mm.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where := q.where()
+
+	query := `
+		DECLARE movies_export NO SCROLL CURSOR FOR
+		SELECT id, created_at, title, year, runtime, genres, average_rating, rating_count, version
+		FROM movies
+		WHERE ` + where.String() + `
+		ORDER BY id`
+
+	_, err = tx.ExecContext(ctx, query, where.args...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("FETCH FORWARD %d FROM movies_export", exportFetchSize))
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+
+		for rows.Next() {
+			var movie Movies
+
+			args := []any{&movie.ID, &movie.CreatedAt, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.AverageRating, &movie.RatingCount, &movie.Version}
+			err = rows.Scan(args...)
+			if err == nil {
+				err = fn(&movie)
+			}
+
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			fetched++
+		}
+
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+// MovieIndexes lists the indexes of the movies table
+func (mm MovieModel) MovieIndexes(ctx context.Context) ([]string, error) {
+	query := `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = 'movies' ORDER BY indexname`
+
+	rows, err := mm.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []string
+
+	for rows.Next() {
+		var index string
+
+		err := rows.Scan(&index)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	return indexes, rows.Err()
+}
+
+// ReindexMovies rebuilds the indexes of the movies table and refreshes its planner statistics. A concurrent
+// rebuild doesn't block writes but takes longer; there's no timeout, ctx bounds it.
+func (mm MovieModel) ReindexMovies(ctx context.Context, concurrently bool) error {
+	query := `REINDEX TABLE movies`
+	if concurrently {
+		query = `REINDEX TABLE CONCURRENTLY movies`
+	}
+
+	_, err := mm.DB.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = mm.DB.ExecContext(ctx, `ANALYZE movies`)
+	return err
+}
+
+// GetMovie reads a movie, trimmed to the given fields when there are any. The id and version are always read.
+func (mm MovieModel) GetMovie(id int64, fields ...string) (*Movies, error) {
+	movie := Movies{fields: fields}
+
+	columns, args := movieSelect(&movie, withFields(fields, "id", "version"))
+	query := `SELECT ` + columns + ` FROM movies WHERE id=$1`
 
-	args := []any{&movie.ID, &movie.CreatedAt, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.Version}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
 	row := mm.DB.QueryRowContext(ctx, query, id)
 	err := row.Scan(args...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, err
+			return nil, ErrRecordNotFound
 		default:
 			return nil, err
 		}
@@ -112,32 +490,310 @@ func (mm MovieModel) GetMovie(id int64) (*Movies, error) {
 	return &movie, nil
 }
 
-func (mm MovieModel) UpdateMovie(movie *Movies) (*Movies, error) {
-	query := `UPDATE movies SET title=$1, year=$2, runtime=$3, genres=$4, version=version+1 WHERE id=$5 AND version=$6 RETURNING version`
+func (mm MovieModel) UpdateMovie(movie *Movies, actorID int64) (*Movies, error) {
+	query := `UPDATE movies SET title=$1, year=$2, runtime=$3, genres=$4, version=version+1 WHERE id=$5 AND version=$6 RETURNING version, average_rating, rating_count`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	args := []any{&movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.ID, &movie.Version}
-	row := mm.DB.QueryRowContext(ctx, query, args...)
-	err := row.Scan(&movie.Version)
+	tx, err := mm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var before Movies
+
+	// a movie deleted or updated since it was read is a conflict either way
+	row := tx.QueryRowContext(ctx, `SELECT id, title, year, runtime, genres, version FROM movies WHERE id=$1 FOR UPDATE`, movie.ID)
+	err = row.Scan(&before.ID, &before.Title, &before.Year, &before.Runtime, pq.Array(&before.Genres), &before.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrEditConflict
+		default:
 			return nil, err
+		}
+	}
+
+	args := []any{&movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.ID, &movie.Version}
+	row = tx.QueryRowContext(ctx, query, args...)
+	err = row.Scan(&movie.Version, &movie.AverageRating, &movie.RatingCount)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrEditConflict
 		default:
 			return nil, err
 		}
 	}
 
+	err = insertRevision(ctx, tx, newRevision(RevisionUpdate, &before, movie, actorID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
 	return movie, nil
 }
 
-type MovieMockModel struct{}
+// DeleteMovie deletes a movie, only at the given version unless it's 0. A version that moved on is an edit conflict.
+func (mm MovieModel) DeleteMovie(id int64, version int32, actorID int64) error {
+	query := `DELETE FROM movies WHERE id=$1 AND (version=$2 OR $2=0) RETURNING id, title, year, runtime, genres, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := mm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var movie Movies
 
-func (mmm MovieMockModel) InsertMovie(m *Movies) error                { return nil }
-func (mmm MovieMockModel) GetMovie(id int64) (*Movies, error)         { return nil, nil }
-func (mmm MovieMockModel) UpdateMovie(movie *Movies) (*Movies, error) { return nil, nil }
-func (mmm MovieMockModel) GetAllMovies(title string, genres []string, filters Filters) ([]*Movies, Metadata, error) {
-	return nil, Metadata{}, nil
+	err = tx.QueryRowContext(ctx, query, id, version).Scan(&movie.ID, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows) && version != 0:
+			return ErrEditConflict
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = insertRevision(ctx, tx, newRevision(RevisionDelete, &movie, nil, actorID))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MovieMockModel is an in-memory movie model, the listings filter, score and sort the movies it was given
+type MovieMockModel struct {
+	Movies []*Movies
+}
+
+// InsertMovie numbers the movie after the ones the mock holds, it isn't added to them
+func (mmm MovieMockModel) InsertMovie(m *Movies, actorID int64) error {
+	m.ID = int64(len(mmm.Movies)) + 1
+	m.Version = 1
+	m.CreatedAt = time.Now()
+
+	return nil
+}
+func (mmm MovieMockModel) GetMovie(id int64, fields ...string) (*Movies, error) {
+	for _, movie := range mmm.Movies {
+		if movie.ID == id {
+			m := *movie
+			m.fields = fields
+			return &m, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+func (mmm MovieMockModel) UpdateMovie(movie *Movies, actorID int64) (*Movies, error) {
+	for _, stored := range mmm.Movies {
+		if stored.ID == movie.ID && stored.Version == movie.Version {
+			stored.Title, stored.Year, stored.Runtime, stored.Genres = movie.Title, movie.Year, movie.Runtime, movie.Genres
+			stored.Version++
+
+			movie.Version = stored.Version
+			return movie, nil
+		}
+	}
+
+	return nil, ErrEditConflict
+}
+func (mmm MovieMockModel) DeleteMovie(id int64, version int32, actorID int64) error { return nil }
+func (mmm MovieMockModel) ExportMovies(ctx context.Context, q MovieQuery, fn func(*Movies) error) error {
+	for _, movie := range mmm.find(q) {
+		err := fn(movie)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAllMovies pages through the movies like MovieModel.GetAllMovies does, seeking past the cursor when there
+// is one and leaving the total out when the filters skip it
+func (mmm MovieMockModel) GetAllMovies(q MovieQuery, filters Filters) ([]*Movies, Metadata, error) {
+	movies := mmm.find(q)
+	keys := filters.orderKeys()
+
+	order := func(a, b *Movies) int {
+		for _, key := range keys {
+			c := compareMovies(key.column, a, b)
+			if key.direction == "DESC" {
+				c = -c
+			}
+
+			if c != 0 {
+				return c
+			}
+		}
+
+		return 0
+	}
+
+	slices.SortStableFunc(movies, order)
+
+	totalRecords := 0
+	if !filters.SkipTotal {
+		totalRecords = len(movies)
+	}
+
+	var c cursor
+	var page []*Movies
+	var more bool
+
+	if filters.Cursor != "" {
+		var err error
+
+		c, err = decodeCursor(filters.Cursor)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		values, err := c.values(keys)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		position := cursorMovie(keys, values)
+
+		if c.Backward {
+			end, _ := slices.BinarySearchFunc(movies, position, order)
+			start := max(end-filters.limit(), 0)
+			page, more = movies[start:end], start > 0
+		} else {
+			start, found := slices.BinarySearchFunc(movies, position, order)
+			if found {
+				start++
+			}
+
+			end := min(start+filters.limit(), len(movies))
+			page, more = movies[start:end], end < len(movies)
+		}
+	} else {
+		start := min(filters.offset(), len(movies))
+		end := min(start+filters.limit(), len(movies))
+		page, more = movies[start:end], end < len(movies)
+	}
+
+	return page, pageMetadata(filters, c, page, more, totalRecords), nil
+}
+
+// cursorMovie is a movie holding the values of a cursor, to compare the movies of the mock with
+func cursorMovie(keys []sortKey, values []any) *Movies {
+	var m Movies
+
+	for i, key := range keys {
+		switch key.column {
+		case "title":
+			m.Title = values[i].(string)
+		case "year":
+			m.Year = values[i].(int32)
+		case "runtime":
+			m.Runtime = Runtime(values[i].(int32))
+		case "average_rating":
+			m.AverageRating = values[i].(float64)
+		case "score":
+			m.Score = values[i].(float64)
+		default:
+			m.ID = values[i].(int64)
+		}
+	}
+
+	return &m
+}
+
+// find returns copies of the movies matching the query, scored when it has search terms
+func (mmm MovieMockModel) find(q MovieQuery) []*Movies {
+	var movies []*Movies
+
+	for _, m := range mmm.Movies {
+		if !q.matches(m) {
+			continue
+		}
+
+		movie := *m
+		movie.fields = q.Fields
+
+		if q.Search != "" {
+			var ok bool
+
+			movie.Score, movie.Highlight, ok = SearchMovie(q.Search, m)
+			if !ok {
+				continue
+			}
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	return movies
+}
+
+// matches tells whether a movie meets the predicates of the query besides the search, like where does in SQL
+func (q MovieQuery) matches(m *Movies) bool {
+	titleTerms := searchTerms(m.Title)
+	for _, term := range searchTerms(q.Title) {
+		if !slices.Contains(titleTerms, term) {
+			return false
+		}
+	}
+
+	for _, genre := range q.Genres {
+		if !slices.Contains(m.Genres, genre) {
+			return false
+		}
+	}
+
+	if len(q.GenresAny) != 0 && !slices.ContainsFunc(q.GenresAny, func(genre string) bool { return slices.Contains(m.Genres, genre) }) {
+		return false
+	}
+
+	if slices.ContainsFunc(q.GenresExclude, func(genre string) bool { return slices.Contains(m.Genres, genre) }) {
+		return false
+	}
+
+	switch {
+	case q.YearMin != 0 && m.Year < q.YearMin, q.YearMax != 0 && m.Year > q.YearMax:
+		return false
+	case q.RuntimeMin != 0 && m.Runtime < q.RuntimeMin, q.RuntimeMax != 0 && m.Runtime > q.RuntimeMax:
+		return false
+	case !q.CreatedAfter.IsZero() && !m.CreatedAt.After(q.CreatedAfter), !q.CreatedBefore.IsZero() && !m.CreatedAt.Before(q.CreatedBefore):
+		return false
+	}
+
+	return true
+}
+
+// compareMovies orders two movies on a sort column
+func compareMovies(column string, a, b *Movies) int {
+	switch column {
+	case "title":
+		return cmp.Compare(a.Title, b.Title)
+	case "year":
+		return cmp.Compare(a.Year, b.Year)
+	case "runtime":
+		return cmp.Compare(a.Runtime, b.Runtime)
+	case "average_rating":
+		return cmp.Compare(a.AverageRating, b.AverageRating)
+	case "score":
+		return cmp.Compare(a.Score, b.Score)
+	default:
+		return cmp.Compare(a.ID, b.ID)
+	}
 }
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"movie-api/internal/validators"
+	"time"
+)
+
+type Person struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	Biography string    `json:"biography,omitempty"`
+	Version   int32     `json:"version"`
+}
+
+func ValidatePerson(v *validators.Validators, p *Person) {
+	v.Check(p.Name != "", "name", validators.CodeRequired, "must be provided")
+	v.Check(len(p.Name) <= 500, "name", validators.CodeTooLong, "must not be more than 500 bytes long")
+	v.Check(len(p.Biography) <= 10_000, "biography", validators.CodeTooLong, "must not be more than 10000 bytes long")
+}
+
+type PersonModel struct {
+	DB *sql.DB
+}
+
+func (m PersonModel) Insert(p *Person) error {
+	query := `INSERT INTO people (name, biography) VALUES ($1, $2) RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, p.Name, p.Biography).Scan(&p.ID, &p.CreatedAt, &p.Version)
+}
+
+func (m PersonModel) Get(id int64) (*Person, error) {
+	query := `SELECT id, created_at, name, biography, version FROM people WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var p Person
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&p.ID, &p.CreatedAt, &p.Name, &p.Biography, &p.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+func (m PersonModel) GetAll(name string, filters Filters) ([]*Person, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, name, biography, version
+		FROM people
+		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`, filters.orderBy(""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	var people []*Person
+
+	for rows.Next() {
+		var p Person
+
+		err := rows.Scan(&totalRecords, &p.ID, &p.CreatedAt, &p.Name, &p.Biography, &p.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		people = append(people, &p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return people, metadata, nil
+}
+
+func (m PersonModel) Update(p *Person) error {
+	query := `
+			UPDATE people
+			SET name = $1, biography = $2, version = version + 1
+			WHERE id = $3 AND version = $4
+			RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, p.Name, p.Biography, p.ID, p.Version).Scan(&p.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m PersonModel) Delete(id int64) error {
+	query := `DELETE FROM people WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -59,7 +59,8 @@ func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
 
 func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
 	query := `insert into users_permissions
-			select $1, permissions.id from permissions where permissions.code = any($2)`
+			select $1, permissions.id from permissions where permissions.code = any($2)
+			on conflict do nothing`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -67,3 +68,75 @@ func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
 	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
 	return err
 }
+
+// GetAll lists the codes of every permission there is
+func (m PermissionModel) GetAll() (Permissions, error) {
+	query := `select code from permissions order by code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions Permissions
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// RemoveForUser takes permissions away from a user, returning how many they had
+func (m PermissionModel) RemoveForUser(userID int64, codes ...string) (int64, error) {
+	query := `delete from users_permissions
+			using permissions
+			where users_permissions.permission_id = permissions.id
+			and users_permissions.user_id = $1 and permissions.code = any($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// PermissionMockModel is an in-memory permission model holding the permissions of each user
+type PermissionMockModel struct {
+	Permissions map[int64]Permissions
+}
+
+func (m PermissionMockModel) GetAllForUser(userID int64) (Permissions, error) {
+	return m.Permissions[userID], nil
+}
+func (m PermissionMockModel) AddForUser(userID int64, codes ...string) error { return nil }
+func (m PermissionMockModel) RemoveForUser(userID int64, codes ...string) (int64, error) {
+	return 0, nil
+}
+func (m PermissionMockModel) GetAll() (Permissions, error) {
+	var all Permissions
+	for _, permissions := range m.Permissions {
+		for _, code := range permissions {
+			if !all.Include(code) {
+				all = append(all, code)
+			}
+		}
+	}
+
+	return all, nil
+}
@@ -0,0 +1,174 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"movie-api/internal/validators"
+	"time"
+)
+
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+	FrequencyOff    = "off"
+)
+
+type Preferences struct {
+	UserID       int64      `json:"-"`
+	CreatedAt    time.Time  `json:"-"`
+	Genres       []string   `json:"genres"`
+	Frequency    string     `json:"frequency"`
+	LastDigestAt *time.Time `json:"last_digest_at,omitempty"`
+	Version      int        `json:"-"`
+}
+
+// Since returns the point in time from which movies have to be included in the next digest
+func (p *Preferences) Since() time.Time {
+	if p.LastDigestAt != nil {
+		return *p.LastDigestAt
+	}
+
+	return p.CreatedAt
+}
+
+func ValidatePreferences(v *validators.Validators, p *Preferences) {
+	v.Check(len(p.Genres) <= 20, "genres", validators.CodeTooLong, "must not contain more than 20 genres")
+	v.Check(validators.Unique(p.Genres), "genres", validators.CodeDuplicate, "genres values cannot be duplicated")
+	v.Check(validators.PermittedValues(p.Frequency, FrequencyDaily, FrequencyWeekly, FrequencyOff), "frequency", validators.CodeInvalid, "must be daily, weekly or off")
+
+	if p.Frequency != FrequencyOff {
+		v.Check(len(p.Genres) != 0, "genres", validators.CodeTooShort, "at least one genre must be followed to receive digests")
+	}
+}
+
+// DigestRecipient groups the preferences of a user with the address the digest has to be sent to
+type DigestRecipient struct {
+	Preferences
+	Name  string
+	Email string
+}
+
+type PreferenceModel struct {
+	DB *sql.DB
+}
+
+// GetForUser returns the stored preferences of a user or the defaults when the user never saved any
+func (m PreferenceModel) GetForUser(userID int64) (*Preferences, error) {
+	query := `SELECT user_id, created_at, genres, frequency, last_digest_at, version
+			FROM notification_preferences WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var p Preferences
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&p.UserID,
+		&p.CreatedAt,
+		pq.Array(&p.Genres),
+		&p.Frequency,
+		&p.LastDigestAt,
+		&p.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &Preferences{UserID: userID, Genres: []string{}, Frequency: FrequencyOff}, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// Upsert stores the preferences of a user, the digest state is never touched from here
+func (m PreferenceModel) Upsert(p *Preferences) error {
+	query := `
+			INSERT INTO notification_preferences (user_id, genres, frequency)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id) DO UPDATE
+			SET genres = EXCLUDED.genres, frequency = EXCLUDED.frequency, version = notification_preferences.version + 1
+			RETURNING created_at, last_digest_at, version`
+
+	args := []any{p.UserID, pq.Array(p.Genres), p.Frequency}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&p.CreatedAt, &p.LastDigestAt, &p.Version)
+}
+
+// GetDue returns the activated users whose digest frequency has elapsed at the given time
+func (m PreferenceModel) GetDue(now time.Time) ([]*DigestRecipient, error) {
+	query := `
+			SELECT p.user_id, p.created_at, p.genres, p.frequency, p.last_digest_at, p.version, users.name, users.email
+			FROM notification_preferences p
+			INNER JOIN users ON users.id = p.user_id
+			WHERE users.activated = true
+			AND cardinality(p.genres) > 0
+			AND (
+				(p.frequency = 'daily' AND COALESCE(p.last_digest_at, p.created_at) <= $1)
+				OR (p.frequency = 'weekly' AND COALESCE(p.last_digest_at, p.created_at) <= $2)
+			)
+			ORDER BY p.user_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, now.Add(-24*time.Hour), now.Add(-7*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*DigestRecipient
+
+	for rows.Next() {
+		var r DigestRecipient
+
+		args := []any{&r.UserID, &r.CreatedAt, pq.Array(&r.Genres), &r.Frequency, &r.LastDigestAt, &r.Version, &r.Name, &r.Email}
+		err := rows.Scan(args...)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, &r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// ClaimDigest records the digest as sent before the email goes out. Only one caller can claim a given
+// digest, so a restart or a second instance never sends it twice and gets ErrEditConflict instead.
+func (m PreferenceModel) ClaimDigest(p *Preferences, sentAt time.Time) error {
+	query := `
+			UPDATE notification_preferences
+			SET last_digest_at = $1, version = version + 1
+			WHERE user_id = $2 AND version = $3
+			RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, sentAt, p.UserID, p.Version).Scan(&p.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	p.LastDigestAt = &sentAt
+
+	return nil
+}
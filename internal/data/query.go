@@ -0,0 +1,82 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+)
+
+// whereClause collects SQL conditions together with their arguments. Conditions use ? for their arguments,
+// which get numbered as $N in the order they were added, so values never end up in the query text.
+type whereClause struct {
+	conditions []string
+	args       []any
+}
+
+func (w *whereClause) add(condition string, args ...any) {
+	var sb strings.Builder
+
+	for _, arg := range args {
+		before, after, found := strings.Cut(condition, "?")
+		if !found {
+			panic("missing placeholder in condition: " + condition)
+		}
+
+		sb.WriteString(before)
+		sb.WriteString(w.arg(arg))
+		condition = after
+	}
+
+	sb.WriteString(condition)
+	w.conditions = append(w.conditions, sb.String())
+}
+
+// arg adds an argument without a condition, like a LIMIT value, and returns its placeholder
+func (w *whereClause) arg(value any) string {
+	w.args = append(w.args, value)
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+// seek adds the keyset condition matching the rows that come after the given values in the order of the keys.
+// It's spelled out as (a > ?) OR (a = ? AND b < ?) ... so keys can mix directions.
+func (w *whereClause) seek(keys []sortKey, values []any) {
+	var branches []string
+	var args []any
+
+	for i, key := range keys {
+		var terms []string
+
+		for j := 0; j < i; j++ {
+			terms = append(terms, keys[j].column+" = ?")
+			args = append(args, values[j])
+		}
+
+		comparison := ">"
+		if key.direction == "DESC" {
+			comparison = "<"
+		}
+
+		terms = append(terms, key.column+" "+comparison+" ?")
+		args = append(args, values[i])
+
+		branches = append(branches, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	w.add("("+strings.Join(branches, " OR ")+")", args...)
+}
+
+// nest returns the conditions added so far and starts over with none, keeping the arguments. It lets a
+// subquery and the query around it share the numbering of their arguments.
+func (w *whereClause) nest() string {
+	conditions := w.String()
+	w.conditions = nil
+
+	return conditions
+}
+
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return "TRUE"
+	}
+
+	return strings.Join(w.conditions, " AND ")
+}
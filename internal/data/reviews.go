@@ -0,0 +1,301 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"movie-api/internal/validators"
+	"time"
+)
+
+var ErrDuplicateReview = errors.New("duplicate review")
+
+type Review struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movie_id"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Rating    int32     `json:"rating"`
+	Body      string    `json:"body,omitempty"`
+	Version   int32     `json:"version"`
+}
+
+func ValidateReview(v *validators.Validators, review *Review) {
+	v.Check(review.Rating >= 1, "rating", validators.CodeOutOfRange, "must be between 1 and 10")
+	v.Check(review.Rating <= 10, "rating", validators.CodeOutOfRange, "must be between 1 and 10")
+	v.Check(len(review.Body) <= 5000, "body", validators.CodeTooLong, "must not be more than 5000 bytes long")
+}
+
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+func (m ReviewModel) Insert(review *Review) error {
+	query := `INSERT INTO reviews (movie_id, user_id, rating, body)
+			VALUES ($1, $2, $3, $4) RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovie(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	args := []any{review.MovieID, review.UserID, review.Rating, review.Body}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&review.ID, &review.CreatedAt, &review.Version)
+	if err != nil {
+		switch {
+		case violates(err, pgUniqueViolation, "reviews_movie_id_user_id_key"):
+			return ErrDuplicateReview
+		case violates(err, pgForeignKeyViolation, "reviews_movie_id_fkey"):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	err = updateRatingAggregates(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m ReviewModel) Get(movieID, id int64) (*Review, error) {
+	query := `SELECT id, movie_id, user_id, created_at, rating, body, version
+			FROM reviews WHERE movie_id = $1 AND id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var review Review
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, id).Scan(
+		&review.ID,
+		&review.MovieID,
+		&review.UserID,
+		&review.CreatedAt,
+		&review.Rating,
+		&review.Body,
+		&review.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &review, nil
+}
+
+func (m ReviewModel) GetAllForMovie(movieID int64, filters Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, movie_id, user_id, created_at, rating, body, version
+		FROM reviews
+		WHERE movie_id = $1
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`, filters.orderBy(""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	var reviews []*Review
+
+	for rows.Next() {
+		var review Review
+
+		args := []any{&totalRecords, &review.ID, &review.MovieID, &review.UserID, &review.CreatedAt, &review.Rating, &review.Body, &review.Version}
+		err := rows.Scan(args...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reviews, metadata, nil
+}
+
+// GetForMovies returns the latest reviews of several movies at once keyed by movie id, at most limit per movie
+func (m ReviewModel) GetForMovies(movieIDs []int64, limit int) (map[int64][]*Review, error) {
+	query := `
+		SELECT id, movie_id, user_id, created_at, rating, body, version
+		FROM (
+			SELECT *, row_number() OVER (PARTITION BY movie_id ORDER BY created_at DESC, id DESC) AS position
+			FROM reviews
+			WHERE movie_id = ANY($1)
+		) AS ranked
+		WHERE position <= $2
+		ORDER BY movie_id, position`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make(map[int64][]*Review)
+
+	for rows.Next() {
+		var review Review
+
+		err := rows.Scan(&review.ID, &review.MovieID, &review.UserID, &review.CreatedAt, &review.Rating, &review.Body, &review.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		reviews[review.MovieID] = append(reviews[review.MovieID], &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+func (m ReviewModel) Update(review *Review) error {
+	query := `
+			UPDATE reviews
+			SET rating = $1, body = $2, version = version + 1
+			WHERE id = $3 AND version = $4
+			RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovie(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	args := []any{review.Rating, review.Body, review.ID, review.Version}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = updateRatingAggregates(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m ReviewModel) Delete(review *Review) error {
+	query := `DELETE FROM reviews WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovie(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, review.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	err = updateRatingAggregates(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockMovie locks the row of a movie before its reviews change, so review writes on the same movie run one
+// after the other and the aggregates of each see the ones committed before it. The lock is taken before the
+// review statement: taken after, it would wait on the key share lock the foreign key check of a concurrent
+// insert holds, while that insert waits on it. FOR NO KEY UPDATE leaves the foreign key checks of other
+// tables free to go on.
+func lockMovie(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	var id int64
+
+	err := tx.QueryRowContext(ctx, `SELECT id FROM movies WHERE id = $1 FOR NO KEY UPDATE`, movieID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateRatingAggregates recomputes the rating columns of a movie inside the transaction that changed its
+// reviews, once lockMovie has locked it
+func updateRatingAggregates(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	query := `
+			UPDATE movies
+			SET (average_rating, rating_count) = (
+				SELECT COALESCE(ROUND(AVG(rating), 2), 0), count(*) FROM reviews WHERE movie_id = $1
+			)
+			WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, movieID)
+	return err
+}
@@ -0,0 +1,205 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	RevisionInsert = "insert"
+	RevisionUpdate = "update"
+	RevisionDelete = "delete"
+)
+
+// FieldChange holds the value of a movie field before and after a revision
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// MovieSnapshot is the state of a movie right after a revision, it's what a restore goes back to
+type MovieSnapshot struct {
+	Title   string   `json:"title"`
+	Year    int32    `json:"year"`
+	Runtime Runtime  `json:"runtime"`
+	Genres  []string `json:"genres"`
+}
+
+type Revision struct {
+	ID        int64                  `json:"id"`
+	MovieID   int64                  `json:"movie_id"`
+	Version   int32                  `json:"version"`
+	Action    string                 `json:"action"`
+	ActorID   *int64                 `json:"actor_id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Changes   map[string]FieldChange `json:"changes"`
+	Snapshot  MovieSnapshot          `json:"-"`
+}
+
+// Apply copies the snapshot of the revision into the given movie
+func (r *Revision) Apply(m *Movies) {
+	m.Title = r.Snapshot.Title
+	m.Year = r.Snapshot.Year
+	m.Runtime = r.Snapshot.Runtime
+	m.Genres = r.Snapshot.Genres
+}
+
+// newRevision builds the revision of a movie going from before to after, either of them is nil on insert and delete
+func newRevision(action string, before, after *Movies, actorID int64) *Revision {
+	rev := &Revision{
+		Action:  action,
+		ActorID: &actorID,
+		Changes: make(map[string]FieldChange),
+	}
+
+	var old, current MovieSnapshot
+
+	if before != nil {
+		old = MovieSnapshot{Title: before.Title, Year: before.Year, Runtime: before.Runtime, Genres: before.Genres}
+		rev.MovieID = before.ID
+		rev.Version = before.Version + 1
+		rev.Snapshot = old
+	}
+
+	if after != nil {
+		current = MovieSnapshot{Title: after.Title, Year: after.Year, Runtime: after.Runtime, Genres: after.Genres}
+		rev.MovieID = after.ID
+		rev.Version = after.Version
+		rev.Snapshot = current
+	}
+
+	if before == nil || after == nil || old.Title != current.Title {
+		rev.Changes["title"] = FieldChange{Old: nullIf(before == nil, old.Title), New: nullIf(after == nil, current.Title)}
+	}
+
+	if before == nil || after == nil || old.Year != current.Year {
+		rev.Changes["year"] = FieldChange{Old: nullIf(before == nil, old.Year), New: nullIf(after == nil, current.Year)}
+	}
+
+	if before == nil || after == nil || old.Runtime != current.Runtime {
+		rev.Changes["runtime"] = FieldChange{Old: nullIf(before == nil, old.Runtime), New: nullIf(after == nil, current.Runtime)}
+	}
+
+	if before == nil || after == nil || !slices.Equal(old.Genres, current.Genres) {
+		rev.Changes["genres"] = FieldChange{Old: nullIf(before == nil, old.Genres), New: nullIf(after == nil, current.Genres)}
+	}
+
+	return rev
+}
+
+func nullIf(missing bool, value any) any {
+	if missing {
+		return nil
+	}
+
+	return value
+}
+
+// insertRevision records the revision inside the transaction that changed the movie
+func insertRevision(ctx context.Context, tx *sql.Tx, rev *Revision) error {
+	changes, err := json.Marshal(rev.Changes)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(rev.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO movie_revisions (movie_id, version, action, actor_id, changes, snapshot)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+
+	args := []any{rev.MovieID, rev.Version, rev.Action, rev.ActorID, string(changes), string(snapshot)}
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&rev.ID, &rev.CreatedAt)
+}
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+func (m RevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*Revision, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, movie_id, version, action, actor_id, created_at, changes, snapshot
+		FROM movie_revisions
+		WHERE movie_id = $1
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`, filters.orderBy(""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	var revisions []*Revision
+
+	for rows.Next() {
+		var rev Revision
+
+		err := scanRevision(rows, &rev, &totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		revisions = append(revisions, &rev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return revisions, metadata, nil
+}
+
+func (m RevisionModel) Get(movieID int64, version int32) (*Revision, error) {
+	query := `SELECT id, movie_id, version, action, actor_id, created_at, changes, snapshot
+			FROM movie_revisions WHERE movie_id = $1 AND version = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rev Revision
+
+	err := scanRevision(m.DB.QueryRowContext(ctx, query, movieID, version), &rev)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rev, nil
+}
+
+// scanRevision decodes a revision row, extra destinations are scanned before the revision columns
+func scanRevision(row interface{ Scan(...any) error }, rev *Revision, extra ...any) error {
+	var changes, snapshot []byte
+
+	args := append(extra, &rev.ID, &rev.MovieID, &rev.Version, &rev.Action, &rev.ActorID, &rev.CreatedAt, &changes, &snapshot)
+	err := row.Scan(args...)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(changes, &rev.Changes)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(snapshot, &rev.Snapshot)
+}
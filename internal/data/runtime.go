@@ -3,37 +3,148 @@ package data
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
-	"strings"
 )
 
 type Runtime int32
 
+// The formats a runtime can be written in, minutes being the default "102 mins"
+const (
+	RuntimeMinutes = "minutes"
+	RuntimeISO8601 = "iso8601"
+	RuntimeHuman   = "human"
+)
+
+var ErrInvalidRuntimeFormat = errors.New(`invalid runtime, must be minutes as a number or "102 mins", "1h 42m" or "PT1H42M"`)
+
+// the runtime patterns capture hours then minutes, the spaced human one only matches when both are there
+var (
+	runtimeMinutesRx     = regexp.MustCompile(`^(\d+) mins?$`)
+	runtimeHumanRx       = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?$`)
+	runtimeHumanSpacedRx = regexp.MustCompile(`^(\d+)h (\d+)m$`)
+	runtimeISO8601Rx     = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?$`)
+)
+
+// Format renders the runtime in one of the runtime formats, unknown ones falling back to minutes
+func (r Runtime) Format(format string) string {
+	switch format {
+	case RuntimeISO8601:
+		if r == 0 {
+			return "PT0M"
+		}
+
+		s := "PT"
+		if r >= 60 {
+			s += fmt.Sprintf("%dH", r/60)
+		}
+		if r%60 != 0 {
+			s += fmt.Sprintf("%dM", r%60)
+		}
+		return s
+	case RuntimeHuman:
+		switch {
+		case r < 60:
+			return fmt.Sprintf("%dm", r)
+		case r%60 == 0:
+			return fmt.Sprintf("%dh", r/60)
+		default:
+			return fmt.Sprintf("%dh %dm", r/60, r%60)
+		}
+	default:
+		return fmt.Sprintf("%d mins", r)
+	}
+}
+
 // MarshalJSON encodes json
 func (r Runtime) MarshalJSON() ([]byte, error) {
-	value := strconv.Quote(fmt.Sprintf("%d mins", r))
+	value := strconv.Quote(r.Format(RuntimeMinutes))
 
 	return []byte(value), nil
 }
 
-// UnmarshalJSON decodes json
+// UnmarshalJSON decodes json, a runtime is either a whole number of minutes or a string in one of the
+// runtime formats. Hours and minutes must both be whole, and minutes under 60 when there are hours.
 func (r *Runtime) UnmarshalJSON(value []byte) error {
-	unmarshalValue, err := strconv.Unquote(string(value))
-	if err != nil {
-		return err
+	if string(value) == "null" {
+		return nil
 	}
 
-	parts := strings.Split(unmarshalValue, " ")
-	if len(parts) != 1 && parts[1] != "mins" {
-		return errors.New("incorrect runtime value format")
+	if len(value) != 0 && value[0] != '"' {
+		minutes, err := strconv.ParseInt(string(value), 10, 32)
+		if err != nil || minutes < 0 {
+			return ErrInvalidRuntimeFormat
+		}
+
+		*r = Runtime(minutes)
+		return nil
 	}
 
-	i, err := strconv.ParseInt(parts[0], 10, 32)
+	unmarshalValue, err := strconv.Unquote(string(value))
+	if err != nil {
+		return ErrInvalidRuntimeFormat
+	}
+
+	runtime, err := ParseRuntime(unmarshalValue)
 	if err != nil {
 		return err
 	}
 
-	*r = Runtime(i)
+	*r = runtime
 
 	return nil
 }
+
+// ParseRuntime reads a runtime written in any of the runtime formats
+func ParseRuntime(s string) (Runtime, error) {
+	if match := runtimeMinutesRx.FindStringSubmatch(s); match != nil {
+		return runtimeOf(0, match[1])
+	}
+
+	for _, rx := range []*regexp.Regexp{runtimeHumanRx, runtimeHumanSpacedRx, runtimeISO8601Rx} {
+		match := rx.FindStringSubmatch(s)
+		if match == nil || (match[1] == "" && match[2] == "") {
+			continue
+		}
+
+		if match[1] == "" {
+			return runtimeOf(0, match[2])
+		}
+
+		hours, err := strconv.ParseInt(match[1], 10, 32)
+		if err != nil {
+			return 0, ErrInvalidRuntimeFormat
+		}
+
+		// "1h 90m" reads like a typo more than like 2h 30m
+		minutes, err := runtimeOf(0, match[2])
+		if err != nil || minutes >= 60 {
+			return 0, ErrInvalidRuntimeFormat
+		}
+
+		return runtimeOf(hours*60, match[2])
+	}
+
+	return 0, ErrInvalidRuntimeFormat
+}
+
+// runtimeOf adds a number of minutes written in decimal to base, checking the sum fits a runtime
+func runtimeOf(base int64, minutes string) (Runtime, error) {
+	n := int64(0)
+
+	if minutes != "" {
+		var err error
+
+		n, err = strconv.ParseInt(minutes, 10, 32)
+		if err != nil {
+			return 0, ErrInvalidRuntimeFormat
+		}
+	}
+
+	if base+n > math.MaxInt32 {
+		return 0, ErrInvalidRuntimeFormat
+	}
+
+	return Runtime(base + n), nil
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestParseRuntime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Runtime
+		ok   bool
+	}{
+		{"102", 0, false},
+		{"102 mins", 102, true},
+		{"1 min", 1, true},
+		{"1h 42m", 102, true},
+		{"1h42m", 102, true},
+		{"2h", 120, true},
+		{"45m", 45, true},
+		{"PT1H42M", 102, true},
+		{"PT2H", 120, true},
+		{"PT0M", 0, true},
+		{"", 0, false},
+		{" 45m", 0, false},
+		{"1h ", 0, false},
+		{"1h  42m", 0, false},
+		{"45m ", 0, false},
+		{"1h 90m", 0, false},
+		{"-5", 0, false},
+		{"102mins", 0, false},
+		{"PT", 0, false},
+		{"99999999999", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRuntime(tt.in)
+
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("ParseRuntime(%q): unexpected error %v", tt.in, err)
+		case !tt.ok && err == nil:
+			t.Errorf("ParseRuntime(%q): got %d, want an error", tt.in, got)
+		case got != tt.want:
+			t.Errorf("ParseRuntime(%q): got %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+// FuzzRuntime checks that every runtime reads back from each of its formats and from its JSON encoding, and
+// that whatever ParseRuntime accepts formats back to the same runtime
+func FuzzRuntime(f *testing.F) {
+	for _, seed := range []string{"0", "102", "102 mins", "1h 42m", "2h", "45m", "PT1H42M", "PT0M", "1h 90m", " 45m"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		runtimes := []Runtime{}
+
+		if n, err := strconv.ParseInt(s, 10, 32); err == nil && n >= 0 {
+			runtimes = append(runtimes, Runtime(n))
+		}
+
+		if r, err := ParseRuntime(s); err == nil {
+			if r < 0 {
+				t.Fatalf("ParseRuntime(%q) = %d, a negative runtime", s, r)
+			}
+			runtimes = append(runtimes, r)
+		}
+
+		for _, r := range runtimes {
+			for _, format := range []string{RuntimeMinutes, RuntimeISO8601, RuntimeHuman} {
+				formatted := r.Format(format)
+
+				got, err := ParseRuntime(formatted)
+				if err != nil || got != r {
+					t.Fatalf("runtime %d as %s is %q, which parses to %d, %v", r, format, formatted, got, err)
+				}
+			}
+
+			js, err := r.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got Runtime
+			if err := got.UnmarshalJSON(js); err != nil || got != r {
+				t.Fatalf("runtime %d encodes to %s, which decodes to %d, %v", r, js, got, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Full-text search ranks a movie on a document made of its title, weighted A, and its genres, weighted B.
+// Movies that don't match every search term fall back to the trigram similarity of their title, so a typo
+// still finds something. Text matches score 1 plus their rank and fuzzy ones their similarity, which keeps
+// every text match ahead of the fuzzy ones.
+const (
+	searchDocument = `(setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', array_to_string(genres, ' ')), 'B'))`
+
+	// searchSimilarityThreshold is the default pg_trgm.similarity_threshold the % operator filters with
+	searchSimilarityThreshold = 0.3
+
+	// searchRankUnit is what ts_rank gives a single term found once in a field of weight 1
+	searchRankUnit = 0.0607927
+
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// searchCondition matches the movies found by the search terms or close enough to them
+func (q MovieQuery) searchCondition(w *whereClause) {
+	w.add("("+searchDocument+" @@ websearch_to_tsquery('simple', ?) OR title % ?)", q.Search, q.Search)
+}
+
+// searchColumns returns the score and highlight expressions of the search, constants when there is none
+func (q MovieQuery) searchColumns(w *whereClause) (string, string) {
+	if q.Search == "" {
+		return "0::double precision", "''"
+	}
+
+	search := "websearch_to_tsquery('simple', " + w.arg(q.Search) + ")"
+	similarity := "similarity(title, " + w.arg(q.Search) + ")"
+
+	score := "(CASE WHEN " + searchDocument + " @@ " + search + " THEN 1 + ts_rank(" + searchDocument + ", " + search + ")" +
+		" ELSE " + similarity + " END)::double precision"
+
+	highlight := "ts_headline('simple', title, " + search + ", 'StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true')"
+
+	return score, highlight
+}
+
+// SearchMovie scores a movie against search terms the way the database does, for the in-memory model.
+// The rank weighs each term by the field it's found in (1 for the title, 0.4 for the genres) instead of
+// reproducing ts_rank exactly, so scores are comparable rather than equal.
+func SearchMovie(search string, m *Movies) (score float64, highlight string, ok bool) {
+	terms := searchTerms(search)
+	if len(terms) == 0 {
+		return 0, "", false
+	}
+
+	titleTerms := searchTerms(m.Title)
+	genreTerms := searchTerms(strings.Join(m.Genres, " "))
+
+	rank := 0.0
+
+	for _, term := range terms {
+		switch {
+		case slices.Contains(titleTerms, term):
+			rank += 1
+		case slices.Contains(genreTerms, term):
+			rank += 0.4
+		default:
+			similarity := trigramSimilarity(search, m.Title)
+			if similarity < searchSimilarityThreshold {
+				return 0, "", false
+			}
+
+			return similarity, m.Title, true
+		}
+	}
+
+	return 1 + rank/float64(len(terms))*searchRankUnit, highlightTerms(m.Title, terms), true
+}
+
+// searchTerms splits text into the lower case words the simple text search configuration indexes
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// highlightTerms wraps the words of s that are search terms like ts_headline does
+func highlightTerms(s string, terms []string) string {
+	var sb strings.Builder
+
+	word := -1
+
+	for i, r := range s + " " {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if word < 0 {
+				word = i
+			}
+			continue
+		}
+
+		if word >= 0 {
+			if slices.Contains(terms, strings.ToLower(s[word:i])) {
+				sb.WriteString(highlightStart + s[word:i] + highlightStop)
+			} else {
+				sb.WriteString(s[word:i])
+			}
+			word = -1
+		}
+
+		if i < len(s) {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// trigramSimilarity is the pg_trgm similarity of two strings: the shared trigrams over all the trigrams of
+// both, every word being padded with two spaces before and one after.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range ta {
+		if tb[trigram] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+
+	for _, word := range searchTerms(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+
+	return set
+}
@@ -47,8 +47,8 @@ func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error
 }
 
 func ValidateTokenPlaintext(v *validators.Validators, tokenPlainText string) {
-	v.Check(tokenPlainText != "", "token", "must be provided")
-	v.Check(len(tokenPlainText) == 26, "token", "must be 26 bytes long")
+	v.Check(tokenPlainText != "", "token", validators.CodeRequired, "must be provided")
+	v.Check(len(tokenPlainText) == 26, "token", validators.CodeInvalid, "must be 26 bytes long")
 }
 
 type TokenModel struct {
@@ -88,3 +88,31 @@ func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
 
 	return err
 }
+
+// CountExpired counts the tokens past their expiry
+func (m TokenModel) CountExpired() (int64, error) {
+	query := `SELECT count(*) FROM tokens WHERE expiry < NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
+}
+
+// DeleteExpired removes the tokens past their expiry, returning how many there were
+func (m TokenModel) DeleteExpired() (int64, error) {
+	query := `DELETE FROM tokens WHERE expiry < NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
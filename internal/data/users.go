@@ -148,19 +148,19 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 }
 
 func ValidateEmail(v *validators.Validators, email string) {
-	v.Check(email != "", "email", "must be provided")
-	v.Check(validators.Matches(email), "email", "must be a valid email address")
+	v.Check(email != "", "email", validators.CodeRequired, "must be provided")
+	v.Check(validators.Matches(email), "email", validators.CodeInvalid, "must be a valid email address")
 }
 
 func ValidatePasswordPlaintext(v *validators.Validators, password string) {
-	v.Check(password != "", "password", "must be provided")
-	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	v.Check(password != "", "password", validators.CodeRequired, "must be provided")
+	v.Check(len(password) >= 8, "password", validators.CodeTooShort, "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "password", validators.CodeTooLong, "must not be more than 72 bytes long")
 }
 
 func ValidateUser(v *validators.Validators, user *User) {
-	v.Check(user.Name != "", "name", "must be provided")
-	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
+	v.Check(user.Name != "", "name", validators.CodeRequired, "must be provided")
+	v.Check(len(user.Name) <= 500, "name", validators.CodeTooLong, "must not be more than 500 bytes long")
 
 	ValidateEmail(v, user.Email)
 
@@ -216,3 +216,29 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 
 	return &user, nil
 }
+
+// UserMockModel is an in-memory user model, GetForToken finds the users by the plaintext of their tokens
+type UserMockModel struct {
+	Users  []*User
+	Tokens map[string]*User
+}
+
+func (m UserMockModel) InsertUser(user *User) error { return nil }
+func (m UserMockModel) UpdateUser(user *User) error { return nil }
+func (m UserMockModel) GetUserByEmail(email string) (*User, error) {
+	for _, user := range m.Users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+func (m UserMockModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+	user, ok := m.Tokens[tokenPlaintext]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	return user, nil
+}
@@ -0,0 +1,344 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// executor runs a validated operation breadth first: every field is resolved for all the objects of its level
+// at once, which is what lets batch resolvers load a level with one query
+type executor struct {
+	schema       *Schema
+	doc          *document
+	in           *inputs
+	ctx          context.Context
+	presentError func(err error) *Error
+	errors       []*Error
+}
+
+// collectedField is a key of the response with the fields of the query that go into it
+type collectedField struct {
+	key    string
+	fields []*field
+}
+
+// selections resolves a selection set on several objects of the same type, paths holding the path of each
+// one in the response
+func (e *executor) selections(obj *Object, sources []any, paths [][]any, selections []selection) []*orderedMap {
+	results := make([]*orderedMap, len(sources))
+	for i := range results {
+		results[i] = &orderedMap{values: make(map[string]any)}
+	}
+
+	for _, cf := range e.collect(obj, selections, nil, make(map[string]bool)) {
+		f := cf.fields[0]
+
+		if f.name == "__typename" {
+			for _, result := range results {
+				result.set(cf.key, obj.Name)
+			}
+			continue
+		}
+
+		def := obj.Fields[f.name]
+
+		fieldPaths := make([][]any, len(paths))
+		for i, path := range paths {
+			fieldPaths[i] = appendPath(path, cf.key)
+		}
+
+		values, failed := e.resolve(def, f, sources, fieldPaths)
+
+		var subselections []selection
+		for _, f := range cf.fields {
+			subselections = append(subselections, f.selections...)
+		}
+
+		completed := e.complete(def.Type, values, fieldPaths, subselections, f.loc)
+
+		_, nonNull := def.Type.(*NonNull)
+
+		for i, result := range results {
+			if nonNull && nilIfEmpty(values[i]) == nil && !failed[i] {
+				e.report(fmt.Errorf("cannot return null for non-null field %s.%s", obj.Name, f.name), f.loc, fieldPaths[i])
+			}
+
+			result.set(cf.key, completed[i])
+		}
+	}
+
+	return results
+}
+
+// resolve runs the resolver of a field for every source, failed tells the sources it failed for
+func (e *executor) resolve(def *Field, f *field, sources []any, paths [][]any) ([]any, []bool) {
+	values := make([]any, len(sources))
+	failed := make([]bool, len(sources))
+
+	args, err := e.schema.coerceArguments(def.Args, f.arguments, e.in)
+	if err == nil && def.Batch != nil {
+		values, err = def.Batch(BatchParams{Context: e.ctx, Sources: sources, Args: args})
+		if err == nil && len(values) != len(sources) {
+			err = fmt.Errorf("graphql: batch resolver of %q returned %d values for %d sources", f.name, len(values), len(sources))
+		}
+	}
+
+	if err != nil {
+		// a failed batch is reported once, at the first object it was for
+		e.report(err, f.loc, paths[0])
+
+		for i := range failed {
+			failed[i] = true
+		}
+
+		return make([]any, len(sources)), failed
+	}
+
+	if def.Batch != nil {
+		return values, failed
+	}
+
+	for i, source := range sources {
+		values[i], err = def.Resolve(ResolveParams{Context: e.ctx, Source: source, Args: args})
+		if err != nil {
+			e.report(err, f.loc, paths[i])
+			values[i], failed[i] = nil, true
+		}
+	}
+
+	return values, failed
+}
+
+// complete turns resolved values into the values of the response according to their type
+func (e *executor) complete(t Type, values []any, paths [][]any, selections []selection, loc Location) []any {
+	out := make([]any, len(values))
+
+	switch t := t.(type) {
+	case *NonNull:
+		return e.complete(t.Of, values, paths, selections, loc)
+	case *List:
+		// the items of every list are completed together and split back afterwards
+		var items []any
+		var itemPaths [][]any
+		counts := make([]int, len(values))
+
+		for i, v := range values {
+			if v == nil {
+				counts[i] = -1
+				continue
+			}
+
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				e.report(fmt.Errorf("expected a list, the resolver returned %T", v), loc, paths[i])
+				counts[i] = -1
+				continue
+			}
+
+			counts[i] = rv.Len()
+			for j := 0; j < rv.Len(); j++ {
+				items = append(items, nilIfEmpty(rv.Index(j).Interface()))
+				itemPaths = append(itemPaths, appendPath(paths[i], j))
+			}
+		}
+
+		completed := e.complete(t.Of, items, itemPaths, selections, loc)
+
+		for i, count := range counts {
+			if count < 0 {
+				continue
+			}
+
+			out[i], completed = completed[:count:count], completed[count:]
+		}
+	case *Object:
+		var sources []any
+		var sourcePaths [][]any
+		var index []int
+
+		for i, v := range values {
+			if v = nilIfEmpty(v); v != nil {
+				sources = append(sources, v)
+				sourcePaths = append(sourcePaths, paths[i])
+				index = append(index, i)
+			}
+		}
+
+		if len(sources) != 0 {
+			for k, result := range e.selections(t, sources, sourcePaths, selections) {
+				out[index[k]] = result
+			}
+		}
+	case *Scalar:
+		for i, v := range values {
+			if v = nilIfEmpty(v); v == nil {
+				continue
+			}
+
+			s, err := t.Serialize(v)
+			if err != nil {
+				e.report(err, loc, paths[i])
+				continue
+			}
+
+			out[i] = s
+		}
+	case *Enum:
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+
+			name, ok := v.(string)
+			if !ok || !t.has(name) {
+				e.report(fmt.Errorf("%s cannot represent %v", t, v), loc, paths[i])
+				continue
+			}
+
+			out[i] = name
+		}
+	}
+
+	return out
+}
+
+// collect gathers the fields of a selection set by response key, expanding fragments and leaving out what
+// @skip and @include drop
+func (e *executor) collect(obj *Object, selections []selection, fields []*collectedField, visited map[string]bool) []*collectedField {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if !e.included(sel.directives) {
+				continue
+			}
+
+			found := false
+			for _, cf := range fields {
+				if cf.key == sel.key() {
+					cf.fields = append(cf.fields, sel)
+					found = true
+				}
+			}
+
+			if !found {
+				fields = append(fields, &collectedField{key: sel.key(), fields: []*field{sel}})
+			}
+		case *fragmentSpread:
+			if visited[sel.name] || !e.included(sel.directives) {
+				continue
+			}
+
+			visited[sel.name] = true
+			fields = e.collect(obj, e.doc.fragments[sel.name].selections, fields, visited)
+		case *inlineFragment:
+			if !e.included(sel.directives) {
+				continue
+			}
+
+			fields = e.collect(obj, sel.selections, fields, visited)
+		}
+	}
+
+	return fields
+}
+
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		args, err := e.schema.coerceArguments(directiveArgs, d.arguments, e.in)
+		if err != nil {
+			continue
+		}
+
+		if d.name == "skip" && args["if"] == true || d.name == "include" && args["if"] == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *executor) report(err error, loc Location, path []any) {
+	var gqlErr *Error
+
+	switch {
+	case errors.As(err, &gqlErr):
+		copied := *gqlErr
+		gqlErr = &copied
+	case e.presentError != nil:
+		gqlErr = e.presentError(err)
+	default:
+		gqlErr = &Error{Message: err.Error()}
+	}
+
+	gqlErr.Locations = []Location{loc}
+	gqlErr.Path = path
+	e.errors = append(e.errors, gqlErr)
+}
+
+func appendPath(path []any, key any) []any {
+	p := make([]any, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, key)
+}
+
+// nilIfEmpty turns nil pointers and maps into nil, nil slices stay empty lists
+func nilIfEmpty(v any) any {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Map, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+
+	return v
+}
+
+// orderedMap is an object of the response, written with its keys in the order of the query
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,161 @@
+// Package graphql executes GraphQL queries and mutations against a schema of Go resolvers. It covers the
+// executable part of the language: operations, variables, aliases, fragments and the @include and @skip
+// directives. There's no introspection and no subscriptions, and a null in a non-null field is reported as
+// an error without nulling its parent.
+//
+// Fields are resolved one level of the response at a time, so a resolver can load what a whole level needs
+// with a single query; see Field.Batch.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is an error of the response. The code of Extensions tells programs what went wrong.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError returns an error for a resolver to return, code goes into its extensions
+func NewError(code, message string) *Error {
+	return &Error{Message: message, Extensions: map[string]any{"code": code}}
+}
+
+const (
+	CodeSyntaxError     = "syntax_error"
+	CodeInvalidQuery    = "invalid_query"
+	CodeQueryTooDeep    = "query_too_deep"
+	CodeQueryTooComplex = "query_too_complex"
+)
+
+func syntaxError(loc Location, message string) *Error {
+	err := NewError(CodeSyntaxError, "syntax error: "+message)
+	err.Locations = []Location{loc}
+	return err
+}
+
+func invalidQuery(loc Location, format string, args ...any) *Error {
+	err := NewError(CodeInvalidQuery, fmt.Sprintf(format, args...))
+	err.Locations = []Location{loc}
+	return err
+}
+
+type Params struct {
+	Context       context.Context
+	Query         string
+	OperationName string
+	Variables     map[string]any
+
+	// PresentError turns the errors of resolvers that aren't an *Error into one, so internal errors don't leak
+	// into responses; without it their message is written as it is
+	PresentError func(err error) *Error
+}
+
+// Result is the response to a query. Data is left out when the query failed before it was executed.
+type Result struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Execute parses, validates and runs the operation of the query. A query going beyond the limits of the
+// schema isn't run at all.
+func (s *Schema) Execute(p Params) *Result {
+	if p.Context == nil {
+		p.Context = context.Background()
+	}
+
+	doc, err := parse(p.Query)
+	if err != nil {
+		return &Result{Errors: []*Error{asError(err)}}
+	}
+
+	op, err := doc.operation(p.OperationName)
+	if err != nil {
+		return &Result{Errors: []*Error{asError(err)}}
+	}
+
+	var root *Object
+
+	switch op.kind {
+	case "query":
+		root = s.Query
+	case "mutation":
+		root = s.Mutation
+	}
+
+	if root == nil {
+		return &Result{Errors: []*Error{invalidQuery(op.loc, "%s operations are not supported", op.kind)}}
+	}
+
+	in, errs := s.coerceVariables(op, p.Variables)
+	if len(errs) != 0 {
+		return &Result{Errors: errs}
+	}
+
+	v := &validator{schema: s, doc: doc, in: in, spreading: make(map[string]bool)}
+	v.directives(op.directives)
+	complexity := v.selections(root, op.selections, 1)
+
+	// fields are only compared once the selections are known to be valid and within the field limit
+	if len(v.errors) == 0 {
+		v.mergeable(root, op.selections)
+	}
+
+	if s.MaxComplexity > 0 && complexity > s.MaxComplexity && len(v.errors) == 0 {
+		err := NewError(CodeQueryTooComplex, fmt.Sprintf("query has a complexity of %d, the maximum is %d", complexity, s.MaxComplexity))
+		err.Extensions["complexity"] = complexity
+		err.Extensions["max_complexity"] = s.MaxComplexity
+		v.errors = append(v.errors, err)
+	}
+
+	if len(v.errors) != 0 {
+		return &Result{Errors: v.errors}
+	}
+
+	e := &executor{schema: s, doc: doc, in: in, ctx: p.Context, presentError: p.PresentError}
+	data := e.selections(root, []any{nil}, [][]any{nil}, op.selections)
+
+	return &Result{Data: data[0], Errors: e.errors}
+}
+
+// operation picks the operation to run, the name can only be left out when there's a single one
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, invalidQuery(doc.operations[1].loc, "the document has several operations, operationName must pick one")
+		}
+
+		return doc.operations[0], nil
+	}
+
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+
+	return nil, NewError(CodeInvalidQuery, fmt.Sprintf("the document has no operation named %q", name))
+}
+
+func asError(err error) *Error {
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		return gqlErr
+	}
+
+	return &Error{Message: err.Error()}
+}
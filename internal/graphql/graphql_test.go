@@ -0,0 +1,426 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	id   int64
+	name string
+}
+
+func newItem(id int64) *item {
+	return &item{id: id, name: "item " + fmt.Sprint(id)}
+}
+
+// testSchema serves items, each with a child and a list of children, the batches count the calls to the
+// batch resolver of children
+func testSchema(t *testing.T, batches *int) *Schema {
+	t.Helper()
+
+	color := &Enum{Name: "Color", Values: []string{"RED", "GREEN"}}
+	filter := &InputObject{Name: "Filter", Fields: map[string]*Argument{
+		"ids":   {Type: &NonNull{&List{Of: &NonNull{ID}}}},
+		"color": {Type: color, Default: "RED"},
+	}}
+
+	itemType := &Object{Name: "Item"}
+	itemType.Fields = map[string]*Field{
+		"id":   {Type: &NonNull{ID}, Resolve: func(p ResolveParams) (any, error) { return p.Source.(*item).id, nil }},
+		"name": {Type: String, Resolve: func(p ResolveParams) (any, error) { return p.Source.(*item).name, nil }},
+		"child": {Type: itemType, Resolve: func(p ResolveParams) (any, error) {
+			return newItem(p.Source.(*item).id * 10), nil
+		}},
+		"children": {
+			Type: &List{Of: &NonNull{itemType}},
+			Args: map[string]*Argument{"first": {Type: Int, Default: 2}},
+			Batch: func(p BatchParams) ([]any, error) {
+				*batches++
+
+				out := make([]any, len(p.Sources))
+				for i, source := range p.Sources {
+					var children []*item
+					for j := 1; j <= p.Args["first"].(int); j++ {
+						children = append(children, newItem(source.(*item).id*10+int64(j)))
+					}
+					out[i] = children
+				}
+
+				return out, nil
+			},
+			Multiplier: func(args map[string]any) int { return args["first"].(int) },
+		},
+		"broken": {Type: &NonNull{String}, Resolve: func(p ResolveParams) (any, error) { return nil, nil }},
+		"failing": {Type: String, Resolve: func(p ResolveParams) (any, error) {
+			return nil, errors.New("the item is out of order")
+		}},
+	}
+
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"item": {
+			Type: itemType,
+			Args: map[string]*Argument{"id": {Type: &NonNull{ID}}},
+			Resolve: func(p ResolveParams) (any, error) {
+				return newItem(p.Args["id"].(int64)), nil
+			},
+		},
+		"items": {
+			Type: &List{Of: itemType},
+			Args: map[string]*Argument{"first": {Type: Int, Default: 3}},
+			Resolve: func(p ResolveParams) (any, error) {
+				var items []*item
+				for i := 1; i <= p.Args["first"].(int); i++ {
+					items = append(items, newItem(int64(i)))
+				}
+				return items, nil
+			},
+			Multiplier: func(args map[string]any) int { return args["first"].(int) },
+		},
+		"echo": {
+			Type: String,
+			Args: map[string]*Argument{
+				"s":      {Type: String},
+				"n":      {Type: Int},
+				"f":      {Type: Float},
+				"b":      {Type: Boolean},
+				"color":  {Type: color},
+				"filter": {Type: filter},
+				"ids":    {Type: &List{Of: ID}},
+			},
+			Resolve: func(p ResolveParams) (any, error) {
+				var keys []string
+				for key := range p.Args {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+
+				var out []string
+				for _, key := range keys {
+					out = append(out, fmt.Sprintf("%s=%v", key, p.Args[key]))
+				}
+				return strings.Join(out, " "), nil
+			},
+		},
+	}}
+
+	s, err := NewSchema(query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// run executes the query and returns its data as JSON with the codes and messages of its errors
+func run(s *Schema, query string, variables map[string]any) (string, []*Error) {
+	res := s.Execute(Params{Query: query, Variables: variables})
+
+	if res.Data == nil {
+		return "", res.Errors
+	}
+
+	js, err := json.Marshal(res.Data)
+	if err != nil {
+		return err.Error(), res.Errors
+	}
+
+	return string(js), res.Errors
+}
+
+func codes(errs []*Error) string {
+	var out []string
+	for _, err := range errs {
+		out = append(out, fmt.Sprint(err.Extensions["code"]))
+	}
+
+	return strings.Join(out, ",")
+}
+
+func TestParse(t *testing.T) {
+	s := testSchema(t, new(int))
+
+	valid := []struct {
+		query string
+		data  string
+	}{
+		{`{ item(id: 1) { id } }`, `{"item":{"id":"1"}}`},
+		{`query { item(id: "2") { id, name } }`, `{"item":{"id":"2","name":"item 2"}}`},
+		{"# a comment\n{ echo(s: \"a\\tb\\u00e9\") }", `{"echo":"s=a\tbé"}`},
+		{"{ echo(s: \"\"\"\n    one\n      two\n  \"\"\") }", `{"echo":"s=one\n  two"}`},
+		{`query Named { echo(n: -3, f: 1.5e2, b: false) }`, `{"echo":"b=false f=150 n=-3"}`},
+		{`{ echo(n: 0, f: -0.05) }`, `{"echo":"f=-0.05 n=0"}`},
+		{`{ echo(ids: [1, "a"], color: GREEN) }`, `{"echo":"color=GREEN ids=[1 a]"}`},
+		{`{ echo(filter: {ids: [4]}) }`, `{"echo":"filter=map[color:RED ids:[4]]"}`},
+		{`{ ...F } fragment F on Query { echo(n: 1) }`, `{"echo":"n=1"}`},
+	}
+
+	for _, tt := range valid {
+		data, errs := run(s, tt.query, nil)
+		if len(errs) != 0 || data != tt.data {
+			t.Errorf("%s: got %s %v, want %s", tt.query, data, errs, tt.data)
+		}
+	}
+
+	invalid := []string{
+		`{`,
+		`{ item(id: 1) { id }`,
+		`{ item(id: ) { id } }`,
+		`{ echo(s: "open) }`,
+		`{ echo(n: 01) }`,
+		`{ echo(n: -00) }`,
+		`{ echo(f: 1.) }`,
+		`query ($id ID) { item(id: $id) { id } }`,
+		`query ($id: ID = $other) { item(id: $id) { id } }`,
+		`fragment F { id }`,
+		`{ item(id: 1) { ... } }`,
+		`mutation`,
+		`{ echo(s: "a") } ?`,
+	}
+
+	for _, query := range invalid {
+		data, errs := run(s, query, nil)
+		if data != "" || codes(errs) != CodeSyntaxError {
+			t.Errorf("%s: got %s %v, want a syntax error", query, data, errs)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := testSchema(t, new(int))
+
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"unknown field", `{ item(id: 1) { title } }`, `cannot query field "title" on type Item`},
+		{"no subfields", `{ item(id: 1) }`, `must have a selection of subfields`},
+		{"subfields of a scalar", `{ item(id: 1) { id { x } } }`, `has no subfields`},
+		{"unknown argument", `{ item(id: 1, size: 2) { id } }`, `unknown argument "size"`},
+		{"missing argument", `{ item { id } }`, `argument "id" of type ID! is required`},
+		{"wrong argument type", `{ echo(n: "one") }`, `argument "n"`},
+		{"unknown enum value", `{ echo(color: BLUE) }`, `argument "color"`},
+		{"unknown fragment", `{ ...Missing }`, `unknown fragment "Missing"`},
+		{"fragment cycle", `{ item(id: 1) { ...A } } fragment A on Item { child { ...A } }`, `fragment "A" spreads itself`},
+		{"wrong fragment type", `{ item(id: 1) { ...Q } } fragment Q on Query { echo }`, `a fragment on Query can't be spread in Item`},
+		{"unknown directive", `{ echo @defer }`, `unknown directive @defer`},
+		{"directive without if", `{ echo @skip }`, `directive @skip`},
+		{"mutation", `mutation { echo }`, `mutation operations are not supported`},
+		{"several operations", `query A { echo } query B { echo }`, `operationName must pick one`},
+		{"different fields", `{ item(id: 1) { a: id a: child { id } } }`, `fields "a" conflict because id and child are different fields`},
+		{"different arguments", `{ a: item(id: 1) { id } a: item(id: 2) { id } }`, `fields "a" conflict because they have different arguments`},
+		{"argument against none", `{ children: items { id } children: items(first: 3) { id } }`, `different arguments`},
+		{"different variables", `query ($a: ID!, $b: ID!) { item(id: $a) { id } item(id: $b) { id } }`, `different arguments`},
+		{"conflicting subfields", `{ item(id: 1) { child { x: id } } item(id: 1) { child { x: name } } }`, `fields "x" conflict`},
+		{"conflict through a fragment", `{ item(id: 1) { ...F id: name } } fragment F on Item { id }`, `fields "id" conflict`},
+		{"conflict through an inline fragment", `{ item(id: 1) { ... on Item { n: name } n: id } }`, `fields "n" conflict`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, errs := run(s, tt.query, map[string]any{"a": "1", "b": "2"})
+			if data != "" || len(errs) == 0 {
+				t.Fatalf("got %s %v, want a validation error", data, errs)
+			}
+
+			if code := errs[0].Extensions["code"]; code != CodeInvalidQuery || !strings.Contains(errs[0].Message, tt.message) {
+				t.Errorf("got %s %q, want %s %q", code, errs[0].Message, CodeInvalidQuery, tt.message)
+			}
+		})
+	}
+
+	mergeable := []struct {
+		query string
+		data  string
+	}{
+		{`{ item(id: 1) { id id } item(id: 1) { name } }`, `{"item":{"id":"1","name":"item 1"}}`},
+		{`{ echo(n: 1, s: "x") echo(s: "x", n: 1) }`, `{"echo":"n=1 s=x"}`},
+		{`{ item(id: 1) { child { id } child { name } } }`, `{"item":{"child":{"id":"10","name":"item 10"}}}`},
+		{`{ item(id: 1) { ...F id } } fragment F on Item { id name }`, `{"item":{"id":"1","name":"item 1"}}`},
+		{`query ($a: ID!) { item(id: $a) { id } item(id: $a) { name } }`, `{"item":{"id":"1","name":"item 1"}}`},
+		{`{ echo(filter: {ids: [1], color: RED}) echo(filter: {color: RED, ids: [1]}) }`, `{"echo":"filter=map[color:RED ids:[1]]"}`},
+	}
+
+	for _, tt := range mergeable {
+		data, errs := run(s, tt.query, map[string]any{"a": "1"})
+		if len(errs) != 0 || data != tt.data {
+			t.Errorf("%s: got %s %v, want %s", tt.query, data, errs, tt.data)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	s := testSchema(t, new(int))
+
+	valid := []struct {
+		query     string
+		variables map[string]any
+		data      string
+	}{
+		{`query ($id: ID!) { item(id: $id) { id } }`, map[string]any{"id": "5"}, `{"item":{"id":"5"}}`},
+		{`query ($id: ID!) { item(id: $id) { id } }`, map[string]any{"id": json.Number("6")}, `{"item":{"id":"6"}}`},
+		{`query ($n: Int = 4) { echo(n: $n) }`, nil, `{"echo":"n=4"}`},
+		{`query ($n: Int = 4) { echo(n: $n) }`, map[string]any{"n": 7.0}, `{"echo":"n=7"}`},
+		{`query ($n: Int) { echo(n: $n) }`, nil, `{"echo":""}`},
+		{`query ($f: Float) { echo(f: $f) }`, map[string]any{"f": 2.0}, `{"echo":"f=2"}`},
+		{`query ($ids: [ID]) { echo(ids: $ids) }`, map[string]any{"ids": "9"}, `{"echo":"ids=[9]"}`},
+		{`query ($c: Color) { echo(color: $c) }`, map[string]any{"c": "GREEN"}, `{"echo":"color=GREEN"}`},
+		{`query ($f: Filter) { echo(filter: $f) }`, map[string]any{"f": map[string]any{"ids": []any{1.0, "x"}}}, `{"echo":"filter=map[color:RED ids:[1 x]]"}`},
+		{`query ($s: Boolean!) { echo @skip(if: $s) item(id: 1) { id } }`, map[string]any{"s": true}, `{"item":{"id":"1"}}`},
+		{`query ($i: Boolean!) { echo @include(if: $i) }`, map[string]any{"i": false}, `{}`},
+	}
+
+	for _, tt := range valid {
+		data, errs := run(s, tt.query, tt.variables)
+		if len(errs) != 0 || data != tt.data {
+			t.Errorf("%s %v: got %s %v, want %s", tt.query, tt.variables, data, errs, tt.data)
+		}
+	}
+
+	invalid := []struct {
+		query     string
+		variables map[string]any
+		message   string
+	}{
+		{`query ($id: ID!) { item(id: $id) { id } }`, nil, `variable $id of type ID! was not provided`},
+		{`query ($id: ID!) { item(id: $id) { id } }`, map[string]any{"id": true}, `variable $id got an invalid value`},
+		{`query ($n: Int) { echo(n: $n) }`, map[string]any{"n": 1.5}, `variable $n got an invalid value`},
+		{`query ($n: Int) { echo(n: $n) }`, map[string]any{"n": 1e10}, `variable $n got an invalid value`},
+		{`query ($c: Color) { echo(color: $c) }`, map[string]any{"c": "BLUE"}, `variable $c got an invalid value`},
+		{`query ($f: Filter) { echo(filter: $f) }`, map[string]any{"f": map[string]any{"color": "RED"}}, `variable $f got an invalid value`},
+		{`query ($f: Filter) { echo(filter: $f) }`, map[string]any{"f": map[string]any{"ids": []any{}, "size": 1}}, `variable $f got an invalid value`},
+		{`query ($n: Int = "four") { echo(n: $n) }`, nil, `default value of $n`},
+		{`query ($n: Int, $n: Int) { echo(n: $n) }`, nil, `only one variable named $n`},
+		{`query ($x: Unknown) { echo }`, nil, `unknown type Unknown`},
+		{`query ($i: Item) { echo }`, nil, `Item is not an input type`},
+		{`query ($s: String) { echo(n: $s) }`, map[string]any{"s": "1"}, `argument "n"`},
+		{`{ echo(n: $undefined) }`, nil, `argument "n"`},
+	}
+
+	for _, tt := range invalid {
+		data, errs := run(s, tt.query, tt.variables)
+		if data != "" || len(errs) == 0 || !strings.Contains(errs[0].Message, tt.message) {
+			t.Errorf("%s %v: got %s %v, want an error with %q", tt.query, tt.variables, data, errs, tt.message)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s := testSchema(t, new(int))
+	s.MaxDepth = 3
+	s.MaxComplexity = 20
+
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{`{ item(id: 1) { child { id } } }`, ""},
+		{`{ item(id: 1) { child { child { id } } } }`, CodeQueryTooDeep},
+		{`{ item(id: 1) { ...C } } fragment C on Item { child { child { id } } }`, CodeQueryTooDeep},
+		{`{ items(first: 9) { id name } }`, ""},
+		{`{ items(first: 10) { id name } }`, CodeQueryTooComplex},
+		{`{ items(first: 3) { children(first: 3) { id } } }`, ""},
+		{`{ items(first: 3) { children(first: 4) { id name } } }`, CodeQueryTooComplex},
+		{`{ items { id } }`, ""},
+	}
+
+	for _, tt := range tests {
+		_, errs := run(s, tt.query, nil)
+		if got := codes(errs); got != tt.code {
+			t.Errorf("%s: got errors %q, want %q", tt.query, got, tt.code)
+		}
+	}
+
+	_, errs := run(s, `{ items(first: 10) { id name } }`, nil)
+	if ext := errs[0].Extensions; ext["complexity"] != 21 || ext["max_complexity"] != 20 {
+		t.Errorf("complexity extensions: got %v", ext)
+	}
+
+	_, errs = run(s, `{ item(id: 1) { child { child { id } } } }`, nil)
+	if ext := errs[0].Extensions; ext["max_depth"] != 3 || len(errs) != 1 {
+		t.Errorf("depth errors: got %v", errs)
+	}
+
+	// every fragment doubles the fields the one before it selects
+	var bomb strings.Builder
+	bomb.WriteString(`{ item(id: 1) { ...F0 } }`)
+	for i := 0; i < 16; i++ {
+		fmt.Fprintf(&bomb, " fragment F%d on Item { a%d: id ...F%d b%d: name ...F%d }", i, i, i+1, i, i+1)
+	}
+	bomb.WriteString(" fragment F16 on Item { id }")
+
+	s.MaxDepth, s.MaxComplexity = 0, 0
+
+	_, errs = run(s, bomb.String(), nil)
+	if codes(errs) != CodeQueryTooComplex {
+		t.Errorf("fragment bomb: got %v, want %s", errs, CodeQueryTooComplex)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	batches := 0
+	s := testSchema(t, &batches)
+
+	data, errs := run(s, `{ items { id children { id } } }`, nil)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	want := `{"items":[{"id":"1","children":[{"id":"11"},{"id":"12"}]},{"id":"2","children":[{"id":"21"},{"id":"22"}]},{"id":"3","children":[{"id":"31"},{"id":"32"}]}]}`
+	if data != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	if batches != 1 {
+		t.Errorf("children were batched %d times, want once for the whole level", batches)
+	}
+
+	batches = 0
+
+	_, errs = run(s, `{ items(first: 2) { children(first: 1) { children { id } } } }`, nil)
+	if len(errs) != 0 || batches != 2 {
+		t.Errorf("nested children: got %d batches and %v, want one batch a level", batches, errs)
+	}
+
+	data, errs = run(s, `{ first: item(id: 1) { __typename name } second: item(id: 2) { name } }`, nil)
+	if want := `{"first":{"__typename":"Item","name":"item 1"},"second":{"name":"item 2"}}`; len(errs) != 0 || data != want {
+		t.Errorf("aliases: got %s %v, want %s", data, errs, want)
+	}
+
+	data, errs = run(s, `{ items(first: 2) { id failing } }`, nil)
+	if want := `{"items":[{"id":"1","failing":null},{"id":"2","failing":null}]}`; data != want {
+		t.Errorf("failing resolver: got %s, want %s", data, want)
+	}
+
+	if len(errs) != 2 || fmt.Sprint(errs[1].Path) != "[items 1 failing]" || errs[1].Message != "the item is out of order" {
+		t.Errorf("failing resolver: got errors %v", errs)
+	}
+
+	res := s.Execute(Params{
+		Query:        `{ item(id: 1) { failing } }`,
+		PresentError: func(err error) *Error { return NewError("internal", "something went wrong") },
+	})
+	if len(res.Errors) != 1 || res.Errors[0].Message != "something went wrong" || res.Errors[0].Extensions["code"] != "internal" {
+		t.Errorf("presented error: got %v", res.Errors)
+	}
+
+	_, errs = run(s, `{ item(id: 1) { broken } }`, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "cannot return null for non-null field Item.broken") {
+		t.Errorf("null in a non-null field: got %v", errs)
+	}
+
+	data, errs = run(s, `query A { echo(n: 1) } query B { echo(n: 2) }`, nil)
+	if len(errs) == 0 {
+		t.Errorf("several operations without a name: got %s", data)
+	}
+
+	res = s.Execute(Params{Query: `query A { echo(n: 1) } query B { echo(n: 2) }`, OperationName: "B"})
+	if js, _ := json.Marshal(res.Data); string(js) != `{"echo":"n=2"}` {
+		t.Errorf("operation B: got %s %v", js, res.Errors)
+	}
+}
@@ -0,0 +1,688 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string
+	name       string
+	variables  []*variableDefinition
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+type variableDefinition struct {
+	name         string
+	typ          *typeRef
+	defaultValue *value
+	loc          Location
+}
+
+// typeRef is a type as written in a variable definition, a named type or a list, either of them non-null
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+
+	if t.nonNull {
+		s += "!"
+	}
+
+	return s
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+// selection is a *field, a *fragmentSpread or an *inlineFragment
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  []*argument
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+// key is the name of the field in the response
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+type directive struct {
+	name      string
+	arguments []*argument
+	loc       Location
+}
+
+type argument struct {
+	name  string
+	value *value
+	loc   Location
+}
+
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+// value is a literal of the document, raw holds the text of scalars, enums and the name of variables
+type value struct {
+	kind   valueKind
+	raw    string
+	list   []*value
+	fields []*argument
+	loc    Location
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func parse(src string) (*document, error) {
+	p := &parser{lex: newLexer(src)}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			op := &operation{kind: "query", loc: p.tok.loc}
+
+			op.selections, err = p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+
+			doc.operations = append(doc.operations, op)
+		case p.peekName("query", "mutation", "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+
+			if doc.fragments[f.name] != nil {
+				return nil, syntaxError(f.loc, fmt.Sprintf("there can be only one fragment named %q", f.name))
+			}
+
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, syntaxError(p.tok.loc, "the document has no operation")
+	}
+
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(punctuator string) bool {
+	return p.tok.kind == tokenPunctuator && p.tok.value == punctuator
+}
+
+func (p *parser) peekName(names ...string) bool {
+	if p.tok.kind != tokenName {
+		return false
+	}
+
+	for _, name := range names {
+		if p.tok.value == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) unexpected() error {
+	return syntaxError(p.tok.loc, "unexpected "+p.tok.String())
+}
+
+// skip advances over the punctuator if it's the current token and reports whether it was
+func (p *parser) skip(punctuator string) (bool, error) {
+	if !p.peek(punctuator) {
+		return false, nil
+	}
+
+	return true, p.advance()
+}
+
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return syntaxError(p.tok.loc, fmt.Sprintf("expected %q, found %s", punctuator, p.tok))
+	}
+
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", syntaxError(p.tok.loc, "expected a name, found "+p.tok.String())
+	}
+
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.value, loc: p.tok.loc}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName {
+		op.name, _ = p.name()
+	}
+
+	if p.peek("(") {
+		op.variables, err = p.variableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	op.directives, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	op.selections, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*variableDefinition, error) {
+	err := p.expect("(")
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []*variableDefinition
+
+	for !p.peek(")") {
+		def := &variableDefinition{loc: p.tok.loc}
+
+		err = p.expect("$")
+		if err != nil {
+			return nil, err
+		}
+
+		def.name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect(":")
+		if err != nil {
+			return nil, err
+		}
+
+		def.typ, err = p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			def.defaultValue, err = p.value(true)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// directives on variables are allowed by the grammar but none applies to them
+		_, err = p.directives()
+		if err != nil {
+			return nil, err
+		}
+
+		defs = append(defs, def)
+	}
+
+	return defs, p.advance()
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	t := &typeRef{}
+
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		t.elem, err = p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect("]")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		t.name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nonNull, err := p.skip("!")
+	t.nonNull = nonNull
+
+	return t, err
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	f := &fragment{loc: p.tok.loc}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peekName("on") {
+		return nil, syntaxError(p.tok.loc, `a fragment can't be named "on"`)
+	}
+
+	f.name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.peekName("on") {
+		return nil, syntaxError(p.tok.loc, `expected "on", found `+p.tok.String())
+	}
+
+	err = p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	f.typeCondition, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	f.directives, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	f.selections, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	loc := p.tok.loc
+
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+
+	var selections []selection
+
+	for !p.peek("}") {
+		var s selection
+
+		if p.peek("...") {
+			s, err = p.fragmentSelection()
+		} else {
+			s, err = p.field()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		selections = append(selections, s)
+	}
+
+	if len(selections) == 0 {
+		return nil, syntaxError(loc, "a selection set can't be empty")
+	}
+
+	return selections, p.advance()
+}
+
+func (p *parser) fragmentSelection() (selection, error) {
+	loc := p.tok.loc
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		spread := &fragmentSpread{loc: loc}
+		spread.name, _ = p.name()
+
+		spread.directives, err = p.directives()
+		if err != nil {
+			return nil, err
+		}
+
+		return spread, nil
+	}
+
+	inline := &inlineFragment{loc: loc}
+
+	if p.peekName("on") {
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		inline.typeCondition, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	inline.directives, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	inline.selections, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return inline, nil
+}
+
+func (p *parser) field() (*field, error) {
+	f := &field{loc: p.tok.loc}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = name
+
+		name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.name = name
+
+	f.arguments, err = p.arguments(false)
+	if err != nil {
+		return nil, err
+	}
+
+	f.directives, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek("{") {
+		f.selections, err = p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*argument, error) {
+	if !p.peek("(") {
+		return nil, nil
+	}
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	var args []*argument
+
+	for !p.peek(")") {
+		arg := &argument{loc: p.tok.loc}
+
+		arg.name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, other := range args {
+			if other.name == arg.name {
+				return nil, syntaxError(arg.loc, fmt.Sprintf("there can be only one argument named %q", arg.name))
+			}
+		}
+
+		err = p.expect(":")
+		if err != nil {
+			return nil, err
+		}
+
+		arg.value, err = p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+	}
+
+	if len(args) == 0 {
+		return nil, syntaxError(p.tok.loc, "an argument list can't be empty")
+	}
+
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var directives []*directive
+
+	for p.peek("@") {
+		d := &directive{loc: p.tok.loc}
+
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		d.name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		d.arguments, err = p.arguments(false)
+		if err != nil {
+			return nil, err
+		}
+
+		directives = append(directives, d)
+	}
+
+	return directives, nil
+}
+
+// value reads a value, constant ones like default values can't hold variables
+func (p *parser) value(constant bool) (*value, error) {
+	v := &value{loc: p.tok.loc, raw: p.tok.value}
+
+	switch p.tok.kind {
+	case tokenInt:
+		v.kind = valueInt
+	case tokenFloat:
+		v.kind = valueFloat
+	case tokenString:
+		v.kind = valueString
+	case tokenName:
+		switch p.tok.value {
+		case "true", "false":
+			v.kind = valueBoolean
+		case "null":
+			v.kind = valueNull
+		default:
+			v.kind = valueEnum
+		}
+	case tokenPunctuator:
+		switch p.tok.value {
+		case "$":
+			if constant {
+				return nil, syntaxError(v.loc, "a variable can't be used in a constant value")
+			}
+
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+
+			v.kind = valueVariable
+			v.raw, err = p.name()
+			return v, err
+		case "[":
+			return p.listValue(v, constant)
+		case "{":
+			return p.objectValue(v, constant)
+		}
+
+		return nil, p.unexpected()
+	default:
+		return nil, p.unexpected()
+	}
+
+	return v, p.advance()
+}
+
+func (p *parser) listValue(v *value, constant bool) (*value, error) {
+	v.kind = valueList
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	for !p.peek("]") {
+		item, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+
+		v.list = append(v.list, item)
+	}
+
+	return v, p.advance()
+}
+
+func (p *parser) objectValue(v *value, constant bool) (*value, error) {
+	v.kind = valueObject
+
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	for !p.peek("}") {
+		f := &argument{loc: p.tok.loc}
+
+		f.name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect(":")
+		if err != nil {
+			return nil, err
+		}
+
+		f.value, err = p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+
+		v.fields = append(v.fields, f)
+	}
+
+	return v, p.advance()
+}
@@ -0,0 +1,326 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Type is a *Scalar, an *Enum, an *Object, an *InputObject, a *List or a *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type. Serialize turns what resolvers return into the value written in the response,
+// Parse turns an input value, already decoded from the document or the variables, into the one resolvers get.
+type Scalar struct {
+	Name      string
+	Serialize func(v any) (any, error)
+	Parse     func(v any) (any, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Enum is a leaf type limited to a set of names, resolvers get and return them as strings
+type Enum struct {
+	Name   string
+	Values []string
+}
+
+func (e *Enum) String() string { return e.Name }
+
+func (e *Enum) has(name string) bool {
+	for _, v := range e.Values {
+		if v == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+// InputObject is the type of an argument holding several fields, resolvers get it as a map[string]any without
+// the fields the query left out
+type InputObject struct {
+	Name   string
+	Fields map[string]*Argument
+}
+
+func (o *InputObject) String() string { return o.Name }
+
+type List struct {
+	Of Type
+}
+
+func (l *List) String() string { return "[" + l.Of.String() + "]" }
+
+type NonNull struct {
+	Of Type
+}
+
+func (n *NonNull) String() string { return n.Of.String() + "!" }
+
+type Argument struct {
+	Type    Type
+	Default any
+}
+
+// Field is a field of an object. A field is resolved with Batch when it has one, once for all the objects of
+// the same level of the response, so a list of movies loads the reviews of every movie in one go; otherwise
+// with Resolve, once per object.
+//
+// Cost is the complexity of the field itself, 1 when zero. Multiplier tells how many items a list field may
+// return for the arguments it got, the complexity of its selections counts that many times.
+type Field struct {
+	Type       Type
+	Args       map[string]*Argument
+	Resolve    func(p ResolveParams) (any, error)
+	Batch      func(p BatchParams) ([]any, error)
+	Cost       int
+	Multiplier func(args map[string]any) int
+}
+
+type ResolveParams struct {
+	Context context.Context
+	Source  any
+	Args    map[string]any
+}
+
+// BatchParams are the parameters of a batch resolver, which must return a value for every source, in order
+type BatchParams struct {
+	Context context.Context
+	Sources []any
+	Args    map[string]any
+}
+
+// Schema holds the entry points of the operations and the limits queries must stay within, a zero limit
+// disables it
+type Schema struct {
+	Query         *Object
+	Mutation      *Object
+	MaxDepth      int
+	MaxComplexity int
+
+	// types are the named types reachable from the entry points, by name
+	types map[string]Type
+}
+
+// NewSchema checks that the types reachable from the entry points have unique names and that every field
+// has a resolver
+func NewSchema(query, mutation *Object) (*Schema, error) {
+	s := &Schema{Query: query, Mutation: mutation, types: make(map[string]Type)}
+
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		s.types[scalar.Name] = scalar
+	}
+
+	roots := []Type{query}
+	if mutation != nil {
+		roots = append(roots, mutation)
+	}
+
+	for _, root := range roots {
+		err := s.register(root)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Schema) register(t Type) error {
+	switch t := t.(type) {
+	case *List:
+		return s.register(t.Of)
+	case *NonNull:
+		return s.register(t.Of)
+	}
+
+	name := t.String()
+
+	if known, ok := s.types[name]; ok {
+		if known != t {
+			return fmt.Errorf("graphql: two types are named %s", name)
+		}
+		return nil
+	}
+
+	s.types[name] = t
+
+	switch t := t.(type) {
+	case *Object:
+		for fieldName, f := range t.Fields {
+			if f.Resolve == nil && f.Batch == nil {
+				return fmt.Errorf("graphql: %s.%s has no resolver", name, fieldName)
+			}
+
+			err := s.register(f.Type)
+			if err != nil {
+				return err
+			}
+
+			for _, arg := range f.Args {
+				err = s.register(arg.Type)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case *InputObject:
+		for _, f := range t.Fields {
+			err := s.register(f.Type)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+var (
+	Int = &Scalar{
+		Name: "Int",
+		Serialize: func(v any) (any, error) {
+			n, ok := toInt64(v)
+			if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int cannot represent %v", v)
+			}
+			return n, nil
+		},
+		Parse: func(v any) (any, error) {
+			n, ok := v.(int64)
+			if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int cannot represent %s", describe(v))
+			}
+			return int(n), nil
+		},
+	}
+	Float = &Scalar{
+		Name: "Float",
+		Serialize: func(v any) (any, error) {
+			if n, ok := toInt64(v); ok {
+				return float64(n), nil
+			}
+			switch f := v.(type) {
+			case float32:
+				return float64(f), nil
+			case float64:
+				return f, nil
+			}
+			return nil, fmt.Errorf("Float cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			switch n := v.(type) {
+			case int64:
+				return float64(n), nil
+			case float64:
+				return n, nil
+			}
+			return nil, fmt.Errorf("Float cannot represent %s", describe(v))
+		},
+	}
+	String = &Scalar{
+		Name: "String",
+		Serialize: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String cannot represent %s", describe(v))
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		Serialize: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent %s", describe(v))
+		},
+	}
+	// ID is written as a string, integer ids are parsed back into int64
+	ID = &Scalar{
+		Name: "ID",
+		Serialize: func(v any) (any, error) {
+			if n, ok := toInt64(v); ok {
+				return strconv.FormatInt(n, 10), nil
+			}
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("ID cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			switch id := v.(type) {
+			case int64:
+				return id, nil
+			case string:
+				if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+					return n, nil
+				}
+				return id, nil
+			}
+			return nil, fmt.Errorf("ID cannot represent %s", describe(v))
+		},
+	}
+)
+
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	}
+
+	return 0, false
+}
+
+// describe writes an input value for an error message
+func describe(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	default:
+		return fmt.Sprint(v)
+	}
+}
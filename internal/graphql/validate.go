@@ -0,0 +1,291 @@
+package graphql
+
+import "strconv"
+
+// validator checks the selections of an operation against the schema before anything runs, adding up the
+// complexity of the query and watching its depth on the way
+type validator struct {
+	schema *Schema
+	doc    *document
+	in     *inputs
+	errors []*Error
+
+	// spreading holds the fragments being expanded, to catch the ones that spread themselves
+	spreading map[string]bool
+	tooDeep   bool
+
+	// fields counts the fields walked, fragments spread many times can make a short query huge
+	fields int
+}
+
+// maxFields bounds the fields a query may select once its fragments are expanded
+const maxFields = 10_000
+
+func (v *validator) report(err *Error) {
+	v.errors = append(v.errors, err)
+}
+
+// selections validates a selection set of an object at the given depth and returns its complexity
+func (v *validator) selections(obj *Object, selections []selection, depth int) int {
+	complexity := 0
+
+	for _, sel := range selections {
+		if v.fields > maxFields {
+			return complexity
+		}
+
+		switch sel := sel.(type) {
+		case *field:
+			v.fields++
+			if v.fields > maxFields {
+				v.report(NewError(CodeQueryTooComplex, "query selects more than "+strconv.Itoa(maxFields)+" fields"))
+				return complexity
+			}
+
+			complexity += v.field(obj, sel, depth)
+		case *fragmentSpread:
+			v.directives(sel.directives)
+
+			f := v.doc.fragments[sel.name]
+			if f == nil {
+				v.report(invalidQuery(sel.loc, "unknown fragment %q", sel.name))
+				continue
+			}
+
+			if v.spreading[sel.name] {
+				v.report(invalidQuery(sel.loc, "fragment %q spreads itself", sel.name))
+				continue
+			}
+
+			if !v.typeCondition(obj, f.typeCondition, sel.loc) {
+				continue
+			}
+
+			v.spreading[sel.name] = true
+			complexity += v.selections(obj, f.selections, depth)
+			delete(v.spreading, sel.name)
+		case *inlineFragment:
+			v.directives(sel.directives)
+
+			if sel.typeCondition != "" && !v.typeCondition(obj, sel.typeCondition, sel.loc) {
+				continue
+			}
+
+			complexity += v.selections(obj, sel.selections, depth)
+		}
+	}
+
+	return complexity
+}
+
+// typeCondition checks that a fragment applies to the object, there are no interfaces or unions a fragment
+// could be about
+func (v *validator) typeCondition(obj *Object, name string, loc Location) bool {
+	if _, ok := v.schema.types[name]; !ok {
+		v.report(invalidQuery(loc, "unknown type %q", name))
+		return false
+	}
+
+	if name != obj.Name {
+		v.report(invalidQuery(loc, "a fragment on %s can't be spread in %s", name, obj.Name))
+		return false
+	}
+
+	return true
+}
+
+func (v *validator) field(obj *Object, f *field, depth int) int {
+	v.directives(f.directives)
+
+	if f.name == "__typename" {
+		if len(f.arguments) != 0 || len(f.selections) != 0 {
+			v.report(invalidQuery(f.loc, "__typename takes no arguments and has no subfields"))
+		}
+		return 0
+	}
+
+	def := obj.Fields[f.name]
+	if def == nil {
+		v.report(invalidQuery(f.loc, "cannot query field %q on type %s", f.name, obj.Name))
+		return 0
+	}
+
+	if v.schema.MaxDepth > 0 && depth > v.schema.MaxDepth && !v.tooDeep {
+		v.tooDeep = true
+
+		err := NewError(CodeQueryTooDeep, "query is nested deeper than the maximum depth of "+strconv.Itoa(v.schema.MaxDepth))
+		err.Locations = []Location{f.loc}
+		err.Extensions["max_depth"] = v.schema.MaxDepth
+		v.report(err)
+	}
+
+	args, err := v.schema.coerceArguments(def.Args, f.arguments, v.in)
+	if err != nil {
+		v.report(invalidQuery(f.loc, "field %q: %s", f.name, err))
+	}
+
+	children := 0
+
+	if child, ok := namedType(def.Type).(*Object); ok {
+		if len(f.selections) == 0 {
+			v.report(invalidQuery(f.loc, "field %q of type %s must have a selection of subfields", f.name, def.Type))
+		}
+
+		children = v.selections(child, f.selections, depth+1)
+	} else if len(f.selections) != 0 {
+		v.report(invalidQuery(f.loc, "field %q of type %s has no subfields", f.name, def.Type))
+	}
+
+	cost := def.Cost
+	if cost == 0 {
+		cost = 1
+	}
+
+	multiplier := 1
+	if def.Multiplier != nil && err == nil {
+		multiplier = max(def.Multiplier(args), 1)
+	}
+
+	return cost + multiplier*children
+}
+
+// mergeable checks that the fields a selection set writes under the same response key can be merged into one:
+// they must select the same field with the same arguments, and their subfields must merge in turn. Fragments
+// only apply to the object they're spread in, so the fields of a key always come from the same parent type.
+func (v *validator) mergeable(obj *Object, selections []selection) {
+	for _, cf := range v.collect(selections, nil, make(map[string]bool)) {
+		first := cf.fields[0]
+		conflict := false
+
+		for _, f := range cf.fields[1:] {
+			var err *Error
+
+			switch {
+			case f.name != first.name:
+				err = invalidQuery(f.loc, "fields %q conflict because %s and %s are different fields", cf.key, first.name, f.name)
+			case !sameArguments(first.arguments, f.arguments):
+				err = invalidQuery(f.loc, "fields %q conflict because they have different arguments", cf.key)
+			default:
+				continue
+			}
+
+			err.Locations = []Location{first.loc, f.loc}
+			v.report(err)
+			conflict = true
+			break
+		}
+
+		def := obj.Fields[first.name]
+		if conflict || def == nil {
+			continue
+		}
+
+		if child, ok := namedType(def.Type).(*Object); ok {
+			var subselections []selection
+			for _, f := range cf.fields {
+				subselections = append(subselections, f.selections...)
+			}
+
+			v.mergeable(child, subselections)
+		}
+	}
+}
+
+// collect gathers the fields of a selection set by response key like the executor does, though with every
+// fragment expanded since the directives could go either way
+func (v *validator) collect(selections []selection, fields []*collectedField, visited map[string]bool) []*collectedField {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			found := false
+			for _, cf := range fields {
+				if cf.key == sel.key() {
+					cf.fields = append(cf.fields, sel)
+					found = true
+				}
+			}
+
+			if !found {
+				fields = append(fields, &collectedField{key: sel.key(), fields: []*field{sel}})
+			}
+		case *fragmentSpread:
+			f := v.doc.fragments[sel.name]
+			if f == nil || visited[sel.name] {
+				continue
+			}
+
+			visited[sel.name] = true
+			fields = v.collect(f.selections, fields, visited)
+		case *inlineFragment:
+			fields = v.collect(sel.selections, fields, visited)
+		}
+	}
+
+	return fields
+}
+
+// sameArguments tells whether two fields are given the same arguments, in any order
+func sameArguments(a, b []*argument) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, arg := range a {
+		found := false
+		for _, other := range b {
+			if arg.name == other.name && sameValue(arg.value, other.value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameValue compares two values as written, a variable is only the same as itself
+func sameValue(a, b *value) bool {
+	if a.kind != b.kind || a.raw != b.raw || len(a.list) != len(b.list) {
+		return false
+	}
+
+	for i := range a.list {
+		if !sameValue(a.list[i], b.list[i]) {
+			return false
+		}
+	}
+
+	return sameArguments(a.fields, b.fields)
+}
+
+func (v *validator) directives(directives []*directive) {
+	for _, d := range directives {
+		if d.name != "include" && d.name != "skip" {
+			v.report(invalidQuery(d.loc, "unknown directive @%s", d.name))
+			continue
+		}
+
+		_, err := v.schema.coerceArguments(directiveArgs, d.arguments, v.in)
+		if err != nil {
+			v.report(invalidQuery(d.loc, "directive @%s: %s", d.name, err))
+		}
+	}
+}
+
+// namedType strips the lists and non-null wrappers off a type
+func namedType(t Type) Type {
+	for {
+		switch wrapper := t.(type) {
+		case *List:
+			t = wrapper.Of
+		case *NonNull:
+			t = wrapper.Of
+		default:
+			return t
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// inputs are the variables of the operation, coerced to their types. Variables with no value and no default
+// are missing from values.
+type inputs struct {
+	values map[string]any
+	types  map[string]Type
+}
+
+// directiveArgs are the arguments of @include and @skip
+var directiveArgs = map[string]*Argument{"if": {Type: &NonNull{Boolean}}}
+
+// coerceVariables checks the variable definitions of the operation and coerces the values the request gave
+// them, reporting every bad one
+func (s *Schema) coerceVariables(op *operation, values map[string]any) (*inputs, []*Error) {
+	in := &inputs{values: make(map[string]any), types: make(map[string]Type)}
+
+	var errs []*Error
+
+	for _, def := range op.variables {
+		if _, ok := in.types[def.name]; ok {
+			errs = append(errs, invalidQuery(def.loc, "there can be only one variable named $%s", def.name))
+			continue
+		}
+
+		t, err := s.inputType(def.typ)
+		if err != nil {
+			errs = append(errs, invalidQuery(def.loc, "variable $%s: %s", def.name, err))
+			continue
+		}
+
+		in.types[def.name] = t
+
+		raw, ok := values[def.name]
+		if !ok {
+			if def.defaultValue != nil {
+				value, err := s.coerceLiteral(t, def.defaultValue, nil)
+				if err != nil {
+					errs = append(errs, invalidQuery(def.loc, "default value of $%s: %s", def.name, err))
+					continue
+				}
+
+				in.values[def.name] = value
+			} else if _, nonNull := t.(*NonNull); nonNull {
+				errs = append(errs, invalidQuery(def.loc, "variable $%s of type %s was not provided", def.name, t))
+			}
+
+			continue
+		}
+
+		value, err := coerceInput(t, raw)
+		if err != nil {
+			errs = append(errs, invalidQuery(def.loc, "variable $%s got an invalid value: %s", def.name, err))
+			continue
+		}
+
+		in.values[def.name] = value
+	}
+
+	return in, errs
+}
+
+// inputType finds the type a variable definition names, which must be an input type
+func (s *Schema) inputType(ref *typeRef) (Type, error) {
+	var t Type
+
+	if ref.elem != nil {
+		elem, err := s.inputType(ref.elem)
+		if err != nil {
+			return nil, err
+		}
+
+		t = &List{Of: elem}
+	} else {
+		named, ok := s.types[ref.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %s", ref.name)
+		}
+
+		switch named.(type) {
+		case *Scalar, *Enum, *InputObject:
+		default:
+			return nil, fmt.Errorf("%s is not an input type", ref.name)
+		}
+
+		t = named
+	}
+
+	if ref.nonNull {
+		t = &NonNull{Of: t}
+	}
+
+	return t, nil
+}
+
+// coerceArguments checks the arguments given to a field or a directive against their definitions and returns
+// their values, defaults included
+func (s *Schema) coerceArguments(defs map[string]*Argument, args []*argument, in *inputs) (map[string]any, error) {
+	values := make(map[string]any)
+
+	for _, arg := range args {
+		if defs[arg.name] == nil {
+			return nil, fmt.Errorf("unknown argument %q", arg.name)
+		}
+	}
+
+	for name, def := range defs {
+		var arg *argument
+		for _, a := range args {
+			if a.name == name {
+				arg = a
+			}
+		}
+
+		if arg == nil || arg.value.kind == valueVariable && !in.has(arg.value.raw) {
+			if arg != nil {
+				// a missing variable still has to be defined and fit the argument
+				_, err := s.coerceLiteral(def.Type, arg.value, in)
+				if err != nil {
+					return nil, fmt.Errorf("argument %q: %w", name, err)
+				}
+			}
+
+			if def.Default != nil {
+				values[name] = def.Default
+			} else if _, nonNull := def.Type.(*NonNull); nonNull {
+				return nil, fmt.Errorf("argument %q of type %s is required", name, def.Type)
+			}
+
+			continue
+		}
+
+		value, err := s.coerceLiteral(def.Type, arg.value, in)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", name, err)
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+func (in *inputs) has(name string) bool {
+	if in == nil {
+		return false
+	}
+
+	_, ok := in.values[name]
+	return ok
+}
+
+// coerceLiteral turns a value written in the document into the Go value of its type
+func (s *Schema) coerceLiteral(t Type, v *value, in *inputs) (any, error) {
+	if v.kind == valueVariable {
+		if in == nil || in.types[v.raw] == nil {
+			return nil, fmt.Errorf("variable $%s is not defined", v.raw)
+		}
+
+		if !fits(in.types[v.raw], t, in.values[v.raw]) {
+			return nil, fmt.Errorf("variable $%s of type %s can't be used where %s is expected", v.raw, in.types[v.raw], t)
+		}
+
+		return in.values[v.raw], nil
+	}
+
+	if nn, ok := t.(*NonNull); ok {
+		if v.kind == valueNull {
+			return nil, fmt.Errorf("expected a value of type %s, found null", t)
+		}
+
+		return s.coerceLiteral(nn.Of, v, in)
+	}
+
+	if v.kind == valueNull {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		// a single value stands for a list of one item
+		if v.kind != valueList {
+			item, err := s.coerceLiteral(t.Of, v, in)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+
+		items := make([]any, len(v.list))
+		for i, item := range v.list {
+			var err error
+			items[i], err = s.coerceLiteral(t.Of, item, in)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+
+		return items, nil
+	case *InputObject:
+		if v.kind != valueObject {
+			return nil, fmt.Errorf("expected an object of type %s", t)
+		}
+
+		args, err := s.coerceArguments(t.Fields, v.fields, in)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t, err)
+		}
+
+		return args, nil
+	case *Enum:
+		if v.kind != valueEnum || !t.has(v.raw) {
+			return nil, fmt.Errorf("%s is not a value of %s", v.raw, t)
+		}
+
+		return v.raw, nil
+	case *Scalar:
+		var raw any
+
+		switch v.kind {
+		case valueInt:
+			n, err := strconv.ParseInt(v.raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s is out of range", v.raw)
+			}
+			raw = n
+		case valueFloat:
+			f, err := strconv.ParseFloat(v.raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s is out of range", v.raw)
+			}
+			raw = f
+		case valueString:
+			raw = v.raw
+		case valueBoolean:
+			raw = v.raw == "true"
+		default:
+			return nil, fmt.Errorf("expected a value of type %s", t)
+		}
+
+		return t.Parse(raw)
+	}
+
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// fits reports whether a variable of type from can be used where type to is expected: the types must match,
+// and a nullable variable only fits a non-null position when it has a value
+func fits(from, to Type, value any) bool {
+	fromNN, fromNonNull := from.(*NonNull)
+	toNN, toNonNull := to.(*NonNull)
+
+	switch {
+	case fromNonNull && toNonNull:
+		return fits(fromNN.Of, toNN.Of, value)
+	case fromNonNull:
+		return fits(fromNN.Of, to, value)
+	case toNonNull:
+		return value != nil && fits(from, toNN.Of, value)
+	}
+
+	fromList, fromIsList := from.(*List)
+	toList, toIsList := to.(*List)
+
+	if fromIsList || toIsList {
+		return fromIsList && toIsList && fits(fromList.Of, toList.Of, nil)
+	}
+
+	return from == to
+}
+
+// coerceInput turns a variable value decoded from JSON into the Go value of its type
+func coerceInput(t Type, v any) (any, error) {
+	if nn, ok := t.(*NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("expected a value of type %s, found null", t)
+		}
+
+		return coerceInput(nn.Of, v)
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		list, ok := v.([]any)
+		if !ok {
+			item, err := coerceInput(t.Of, v)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+
+		items := make([]any, len(list))
+		for i, item := range list {
+			var err error
+			items[i], err = coerceInput(t.Of, item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+
+		return items, nil
+	case *InputObject:
+		object, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected an object of type %s", t)
+		}
+
+		for name := range object {
+			if t.Fields[name] == nil {
+				return nil, fmt.Errorf("%s has no field %q", t, name)
+			}
+		}
+
+		values := make(map[string]any)
+
+		for name, f := range t.Fields {
+			value, ok := object[name]
+			if !ok {
+				if f.Default != nil {
+					values[name] = f.Default
+				} else if _, nonNull := f.Type.(*NonNull); nonNull {
+					return nil, fmt.Errorf("field %q of type %s is required", name, f.Type)
+				}
+				continue
+			}
+
+			value, err := coerceInput(f.Type, value)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", name, err)
+			}
+
+			values[name] = value
+		}
+
+		return values, nil
+	case *Enum:
+		name, ok := v.(string)
+		if !ok || !t.has(name) {
+			return nil, fmt.Errorf("%s is not a value of %s", describe(v), t)
+		}
+
+		return name, nil
+	case *Scalar:
+		return t.Parse(jsonNumber(v))
+	}
+
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// jsonNumber turns the numbers JSON decoding gives into the int64 and float64 of literals, integral ones
+// into int64
+func jsonNumber(v any) any {
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n)
+		}
+	}
+
+	return v
+}
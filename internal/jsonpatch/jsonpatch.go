@@ -0,0 +1,352 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7386) and JSON Patch (RFC 6902) documents to JSON values
+// decoded into any, that is maps, slices, strings, float64, bools and nil.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operation is one step of a JSON Patch. Value is nil when the member is missing and "null" when it's null.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Error is the failure of an operation, Path is the JSON Pointer it failed at
+type Error struct {
+	Index   int
+	Op      string
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("operation %d (%s) at %q: %s", e.Index, e.Op, e.Path, e.Message)
+}
+
+// Merge applies a merge patch to doc: objects are merged member by member, null removes a member and
+// anything else replaces the target.
+func Merge(doc, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	docObject, ok := doc.(map[string]any)
+	if !ok {
+		docObject = make(map[string]any)
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(docObject, key)
+			continue
+		}
+
+		docObject[key] = Merge(docObject[key], value)
+	}
+
+	return docObject
+}
+
+// Apply runs the operations of a patch on doc in order. The shape of every operation is checked first and all
+// the malformed ones are reported; after that the patch stops at the first operation that fails, since a
+// patch is applied as a whole or not at all. doc is modified in place.
+func Apply(doc any, ops []Operation) (any, []*Error) {
+	var errs []*Error
+
+	for i, op := range ops {
+		err := check(op)
+		if err != "" {
+			errs = append(errs, &Error{Index: i, Op: op.Op, Path: op.Path, Message: err})
+		}
+	}
+
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	for i, op := range ops {
+		var err error
+
+		doc, err = apply(doc, op)
+		if err != nil {
+			return nil, []*Error{{Index: i, Op: op.Op, Path: op.Path, Message: err.Error()}}
+		}
+	}
+
+	return doc, nil
+}
+
+func check(op Operation) string {
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return "value must be provided"
+		}
+	case "move", "copy":
+		if _, err := parsePointer(op.From); err != nil {
+			return "from " + err.Error()
+		}
+	case "remove":
+	default:
+		return "op must be add, remove, replace, move, copy or test"
+	}
+
+	if _, err := parsePointer(op.Path); err != nil {
+		return "path " + err.Error()
+	}
+
+	if op.Op == "move" && strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+		return "a value cannot be moved into one of its children"
+	}
+
+	if op.Value != nil && !json.Valid(op.Value) {
+		return "value must be valid JSON"
+	}
+
+	return ""
+}
+
+func apply(doc any, op Operation) (any, error) {
+	path, _ := parsePointer(op.Path)
+	from, _ := parsePointer(op.From)
+
+	switch op.Op {
+	case "add":
+		var value any
+		_ = json.Unmarshal(op.Value, &value)
+		return add(doc, path, value)
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "replace":
+		var value any
+		_ = json.Unmarshal(op.Value, &value)
+
+		doc, _, err := remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "move":
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value))
+	default:
+		var value any
+		_ = json.Unmarshal(op.Value, &value)
+
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("value is %s", mustMarshal(current))
+		}
+		return doc, nil
+	}
+}
+
+// parsePointer splits a JSON Pointer into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("must be a JSON Pointer starting with /")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func get(doc any, tokens []string) (any, error) {
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			doc = value
+		case []any:
+			i, err := index(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("cannot reference %q inside a scalar value", token)
+		}
+	}
+
+	return doc, nil
+}
+
+func add(doc any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	token, rest := tokens[0], tokens[1:]
+
+	switch node := doc.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			node[token] = value
+			return node, nil
+		}
+
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("member %q does not exist", token)
+		}
+
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+
+		node[token] = child
+		return node, nil
+	case []any:
+		if len(rest) == 0 {
+			if token == "-" {
+				return append(node, value), nil
+			}
+
+			i, err := index(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+
+			return append(node[:i], append([]any{value}, node[i:]...)...), nil
+		}
+
+		i, err := index(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		node[i], err = add(node[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("cannot reference %q inside a scalar value", token)
+	}
+}
+
+// remove takes the value at tokens out of doc and returns both
+func remove(doc any, tokens []string) (any, any, error) {
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+
+	token, rest := tokens[0], tokens[1:]
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q does not exist", token)
+		}
+
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, child, nil
+		}
+
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		node[token] = child
+		return node, removed, nil
+	case []any:
+		i, err := index(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(rest) == 0 {
+			removed := node[i]
+			return append(node[:i], node[i+1:]...), removed, nil
+		}
+
+		child, removed, err := remove(node[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		node[i] = child
+		return node, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot reference %q inside a scalar value", token)
+	}
+}
+
+// index parses an array index token, it must be at most max
+func index(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+
+	if i > max {
+		return 0, fmt.Errorf("index %d is out of bounds", i)
+	}
+
+	return i, nil
+}
+
+func deepCopy(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for k, v := range value {
+			copied[k] = deepCopy(v)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(value))
+		for i, v := range value {
+			copied[i] = deepCopy(v)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+func mustMarshal(value any) string {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(js)
+}
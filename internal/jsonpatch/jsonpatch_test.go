@@ -0,0 +1,234 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, js string) any {
+	t.Helper()
+
+	var value any
+	if err := json.Unmarshal([]byte(js), &value); err != nil {
+		t.Fatalf("%s: %v", js, err)
+	}
+
+	return value
+}
+
+// TestApply runs the examples of RFC 6902 Appendix A, but for A.13: encoding/json keeps the last of duplicate
+// members rather than rejecting them, so that document can't be told apart from a well-formed one
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string // empty when the patch fails
+		index int    // of the failing operation
+	}{
+		{
+			name:  "A.1 adding an object member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "A.2 adding an array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "A.3 removing an object member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "A.4 removing an array element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "A.5 replacing a value",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "A.6 moving a value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "A.7 moving an array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:  "A.8 testing a value: success",
+			doc:   `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			want:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:  "A.9 testing a value: error",
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+		},
+		{
+			name:  "A.10 adding a nested member object",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:  "A.11 ignoring unrecognized elements",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			want:  `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:  "A.12 adding to a nonexistent target",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+		},
+		{
+			name:  "A.14 ~ escape ordering",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:  "A.15 comparing strings and numbers",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": "10"}]`,
+		},
+		{
+			name:  "A.16 adding an array value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			name:  "adding past the end of an array",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/2", "value": "qux"}]`,
+		},
+		{
+			name:  "removing past the end of an array",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+		},
+		{
+			name:  "a leading zero index",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/01"}]`,
+		},
+		{
+			name:  "replacing a missing member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "qux"}]`,
+		},
+		{
+			name:  "copying a missing member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "copy", "from": "/baz", "path": "/qux"}]`,
+		},
+		{
+			name:  "moving a value into one of its children",
+			doc:   `{"foo": {"bar": "baz"}}`,
+			patch: `[{"op": "move", "from": "/foo", "path": "/foo/bar/qux"}]`,
+		},
+		{
+			name:  "stopping at the first failing operation",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}, {"op": "remove", "path": "/qux"}, {"op": "remove", "path": "/foo"}]`,
+			index: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []Operation
+			if err := json.Unmarshal([]byte(tt.patch), &ops); err != nil {
+				t.Fatal(err)
+			}
+
+			got, errs := Apply(decode(t, tt.doc), ops)
+
+			if tt.want == "" {
+				if len(errs) != 1 || errs[0].Index != tt.index {
+					t.Fatalf("got %v and the errors %v, want operation %d to fail", got, errs, tt.index)
+				}
+				return
+			}
+
+			if len(errs) != 0 {
+				t.Fatalf("got the errors %v", errs)
+			}
+
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyMalformed(t *testing.T) {
+	ops := []Operation{
+		{Op: "add", Path: "/foo"},
+		{Op: "remove", Path: "/foo"},
+		{Op: "rename", Path: "/foo"},
+		{Op: "copy", From: "foo", Path: "/bar"},
+		{Op: "replace", Path: "foo", Value: json.RawMessage(`1`)},
+	}
+
+	_, errs := Apply(decode(t, `{"foo": "bar"}`), ops)
+
+	var indexes []int
+	for _, err := range errs {
+		indexes = append(indexes, err.Index)
+	}
+
+	if !reflect.DeepEqual(indexes, []int{0, 2, 3, 4}) {
+		t.Errorf("got the errors %v, want operations 0, 2, 3 and 4 reported", errs)
+	}
+}
+
+// TestMerge runs the examples of RFC 7386 Appendix A
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "c"}`, `{"a": ["b"]}`, `{"a": ["b"]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`["a", "b"]`, `["c", "d"]`, `["c", "d"]`},
+		{`{"a": "b"}`, `["c"]`, `["c"]`},
+		{`{"a": "foo"}`, `null`, `null`},
+		{`{"a": "foo"}`, `"bar"`, `"bar"`},
+		{`{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{`[1, 2]`, `{"a": "b", "c": null}`, `{"a": "b"}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+	}
+
+	for _, tt := range tests {
+		got := Merge(decode(t, tt.doc), decode(t, tt.patch))
+
+		if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s merged with %s: got %v, want %v", tt.doc, tt.patch, got, want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package msgpack encodes and decodes MessagePack for the values JSON has: maps with string keys, slices,
+// strings, numbers, booleans and nil. The API converts to and from JSON at the edges, so this is all it needs.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+var ErrUnsupportedType = errors.New("msgpack: unsupported type")
+
+// FromJSON converts a JSON document to MessagePack. Object keys are written sorted.
+func FromJSON(js []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(js))
+	d.UseNumber()
+
+	var value any
+
+	err := d.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = encode(&buf, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ToJSON converts a MessagePack document to JSON
+func ToJSON(data []byte) ([]byte, error) {
+	r := bytes.NewReader(data)
+
+	value, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Len() != 0 {
+		return nil, errors.New("msgpack: more than one value in the document")
+	}
+
+	return json.Marshal(value)
+}
+
+func encode(buf *bytes.Buffer, value any) error {
+	switch value := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if value {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			encodeInt(buf, i)
+			return nil
+		}
+
+		f, err := value.Float64()
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		encodeLength(buf, len(value), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(value)
+	case []any:
+		encodeLength(buf, len(value), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range value {
+			err := encode(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		encodeLength(buf, len(value), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			encode(buf, key)
+
+			err := encode(buf, value[key])
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w %T", ErrUnsupportedType, value)
+	}
+
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 127, i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.Write([]byte{0xd0, byte(i)})
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// encodeLength writes the header of a string, array or map: the fix format when the length fits in
+// fixMax, then the 8, 16 and 32 bit ones. Arrays and maps have no 8 bit format, their code is 0.
+func encodeLength(buf *bytes.Buffer, n int, fix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		buf.Write([]byte{code8, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func decode(r *bytes.Reader) (any, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code >= 0x80 && code <= 0x8f:
+		return decodeMap(r, int(code&0x0f))
+	case code >= 0x90 && code <= 0x9f:
+		return decodeArray(r, int(code&0x0f))
+	case code >= 0xa0 && code <= 0xbf:
+		return decodeString(r, int(code&0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xca:
+		var bits uint32
+		err = binary.Read(r, binary.BigEndian, &bits)
+		return float64(math.Float32frombits(bits)), err
+	case 0xcb:
+		var bits uint64
+		err = binary.Read(r, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readUint(r, 1<<(code-0xcc))
+		if n > math.MaxInt64 {
+			return float64(n), err
+		}
+		return int64(n), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		n, err := readUint(r, size)
+		return int64(n<<(64-8*size)) >> (64 - 8*size), err
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		// binary data has no JSON counterpart, it's read as a string
+		size := map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4, 0xc4: 1, 0xc5: 2, 0xc6: 4}[code]
+		n, err := readUint(r, size)
+		if err != nil {
+			return nil, err
+		}
+		return decodeString(r, int(n))
+	case 0xdc, 0xdd:
+		n, err := readUint(r, 2<<(code-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeArray(r, int(n))
+	case 0xde, 0xdf:
+		n, err := readUint(r, 2<<(code-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMap(r, int(n))
+	default:
+		return nil, fmt.Errorf("%w 0x%02x", ErrUnsupportedType, code)
+	}
+}
+
+func readUint(r *bytes.Reader, size int) (uint64, error) {
+	b := make([]byte, 8)
+
+	_, err := io.ReadFull(r, b[8-size:])
+	if err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func decodeString(r *bytes.Reader, n int) (any, error) {
+	if n > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return string(b), err
+}
+
+func decodeArray(r *bytes.Reader, n int) (any, error) {
+	// every item takes at least a byte, which bounds what a hostile length can allocate
+	if n > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	items := make([]any, n)
+	for i := range items {
+		var err error
+
+		items[i], err = decode(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+func decodeMap(r *bytes.Reader, n int) (any, error) {
+	if 2*n > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		key, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+
+		s, ok := key.(string)
+		if !ok {
+			return nil, errors.New("msgpack: map keys must be strings")
+		}
+
+		m[s], err = decode(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,945 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: movie/v1/movie.proto
+
+// The movie service serves the movies of the REST API to internal callers. Calls carry the authentication
+// token of the REST API in an "authorization: Bearer TOKEN" metadata entry, and need the same permissions
+// as the matching routes.
+
+package moviev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Movie struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Year  int32                  `protobuf:"varint,3,opt,name=year,proto3" json:"year,omitempty"`
+	// runtime is in minutes
+	Runtime       int32    `protobuf:"varint,4,opt,name=runtime,proto3" json:"runtime,omitempty"`
+	Genres        []string `protobuf:"bytes,5,rep,name=genres,proto3" json:"genres,omitempty"`
+	AverageRating float64  `protobuf:"fixed64,6,opt,name=average_rating,json=averageRating,proto3" json:"average_rating,omitempty"`
+	RatingCount   int32    `protobuf:"varint,7,opt,name=rating_count,json=ratingCount,proto3" json:"rating_count,omitempty"`
+	Version       int32    `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Movie) Reset() {
+	*x = Movie{}
+	mi := &file_movie_v1_movie_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Movie) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Movie) ProtoMessage() {}
+
+func (x *Movie) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Movie.ProtoReflect.Descriptor instead.
+func (*Movie) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Movie) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Movie) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Movie) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *Movie) GetRuntime() int32 {
+	if x != nil {
+		return x.Runtime
+	}
+	return 0
+}
+
+func (x *Movie) GetGenres() []string {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+func (x *Movie) GetAverageRating() float64 {
+	if x != nil {
+		return x.AverageRating
+	}
+	return 0
+}
+
+func (x *Movie) GetRatingCount() int32 {
+	if x != nil {
+		return x.RatingCount
+	}
+	return 0
+}
+
+func (x *Movie) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ListMoviesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Q             string                 `protobuf:"bytes,1,opt,name=q,proto3" json:"q,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Genres        []string               `protobuf:"bytes,3,rep,name=genres,proto3" json:"genres,omitempty"`
+	GenresAny     []string               `protobuf:"bytes,4,rep,name=genres_any,json=genresAny,proto3" json:"genres_any,omitempty"`
+	GenresExclude []string               `protobuf:"bytes,5,rep,name=genres_exclude,json=genresExclude,proto3" json:"genres_exclude,omitempty"`
+	YearMin       int32                  `protobuf:"varint,6,opt,name=year_min,json=yearMin,proto3" json:"year_min,omitempty"`
+	YearMax       int32                  `protobuf:"varint,7,opt,name=year_max,json=yearMax,proto3" json:"year_max,omitempty"`
+	RuntimeMin    int32                  `protobuf:"varint,8,opt,name=runtime_min,json=runtimeMin,proto3" json:"runtime_min,omitempty"`
+	RuntimeMax    int32                  `protobuf:"varint,9,opt,name=runtime_max,json=runtimeMax,proto3" json:"runtime_max,omitempty"`
+	CreatedAfter  *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	CreatedBefore *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	// page defaults to 1 and page_size to 20
+	Page     int32 `protobuf:"varint,12,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize int32 `protobuf:"varint,13,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// sort defaults to id, or to -score when searching with q
+	Sort   string `protobuf:"bytes,14,opt,name=sort,proto3" json:"sort,omitempty"`
+	Cursor string `protobuf:"bytes,15,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// skip_total leaves the total out of the metadata, which saves counting the matches
+	SkipTotal bool `protobuf:"varint,16,opt,name=skip_total,json=skipTotal,proto3" json:"skip_total,omitempty"`
+	// fields trims the movies to a fieldset
+	Fields        []string `protobuf:"bytes,17,rep,name=fields,proto3" json:"fields,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMoviesRequest) Reset() {
+	*x = ListMoviesRequest{}
+	mi := &file_movie_v1_movie_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMoviesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMoviesRequest) ProtoMessage() {}
+
+func (x *ListMoviesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMoviesRequest.ProtoReflect.Descriptor instead.
+func (*ListMoviesRequest) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListMoviesRequest) GetQ() string {
+	if x != nil {
+		return x.Q
+	}
+	return ""
+}
+
+func (x *ListMoviesRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *ListMoviesRequest) GetGenres() []string {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+func (x *ListMoviesRequest) GetGenresAny() []string {
+	if x != nil {
+		return x.GenresAny
+	}
+	return nil
+}
+
+func (x *ListMoviesRequest) GetGenresExclude() []string {
+	if x != nil {
+		return x.GenresExclude
+	}
+	return nil
+}
+
+func (x *ListMoviesRequest) GetYearMin() int32 {
+	if x != nil {
+		return x.YearMin
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetYearMax() int32 {
+	if x != nil {
+		return x.YearMax
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetRuntimeMin() int32 {
+	if x != nil {
+		return x.RuntimeMin
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetRuntimeMax() int32 {
+	if x != nil {
+		return x.RuntimeMax
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetCreatedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return nil
+}
+
+func (x *ListMoviesRequest) GetCreatedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return nil
+}
+
+func (x *ListMoviesRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListMoviesRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListMoviesRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListMoviesRequest) GetSkipTotal() bool {
+	if x != nil {
+		return x.SkipTotal
+	}
+	return false
+}
+
+func (x *ListMoviesRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type ListMoviesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Movies        []*Movie               `protobuf:"bytes,1,rep,name=movies,proto3" json:"movies,omitempty"`
+	Metadata      *Metadata              `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMoviesResponse) Reset() {
+	*x = ListMoviesResponse{}
+	mi := &file_movie_v1_movie_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMoviesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMoviesResponse) ProtoMessage() {}
+
+func (x *ListMoviesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMoviesResponse.ProtoReflect.Descriptor instead.
+func (*ListMoviesResponse) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListMoviesResponse) GetMovies() []*Movie {
+	if x != nil {
+		return x.Movies
+	}
+	return nil
+}
+
+func (x *ListMoviesResponse) GetMetadata() *Metadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type Metadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CurrentPage   int32                  `protobuf:"varint,1,opt,name=current_page,json=currentPage,proto3" json:"current_page,omitempty"`
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	FirstPage     int32                  `protobuf:"varint,3,opt,name=first_page,json=firstPage,proto3" json:"first_page,omitempty"`
+	LastPage      int32                  `protobuf:"varint,4,opt,name=last_page,json=lastPage,proto3" json:"last_page,omitempty"`
+	TotalRecords  int32                  `protobuf:"varint,5,opt,name=total_records,json=totalRecords,proto3" json:"total_records,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,6,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	PrevCursor    string                 `protobuf:"bytes,7,opt,name=prev_cursor,json=prevCursor,proto3" json:"prev_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metadata) Reset() {
+	*x = Metadata{}
+	mi := &file_movie_v1_movie_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metadata) ProtoMessage() {}
+
+func (x *Metadata) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metadata.ProtoReflect.Descriptor instead.
+func (*Metadata) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Metadata) GetCurrentPage() int32 {
+	if x != nil {
+		return x.CurrentPage
+	}
+	return 0
+}
+
+func (x *Metadata) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *Metadata) GetFirstPage() int32 {
+	if x != nil {
+		return x.FirstPage
+	}
+	return 0
+}
+
+func (x *Metadata) GetLastPage() int32 {
+	if x != nil {
+		return x.LastPage
+	}
+	return 0
+}
+
+func (x *Metadata) GetTotalRecords() int32 {
+	if x != nil {
+		return x.TotalRecords
+	}
+	return 0
+}
+
+func (x *Metadata) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+func (x *Metadata) GetPrevCursor() string {
+	if x != nil {
+		return x.PrevCursor
+	}
+	return ""
+}
+
+type GetMovieRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Fields        []string               `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMovieRequest) Reset() {
+	*x = GetMovieRequest{}
+	mi := &file_movie_v1_movie_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMovieRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMovieRequest) ProtoMessage() {}
+
+func (x *GetMovieRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMovieRequest.ProtoReflect.Descriptor instead.
+func (*GetMovieRequest) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetMovieRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GetMovieRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type CreateMovieRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Year          int32                  `protobuf:"varint,2,opt,name=year,proto3" json:"year,omitempty"`
+	Runtime       int32                  `protobuf:"varint,3,opt,name=runtime,proto3" json:"runtime,omitempty"`
+	Genres        []string               `protobuf:"bytes,4,rep,name=genres,proto3" json:"genres,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateMovieRequest) Reset() {
+	*x = CreateMovieRequest{}
+	mi := &file_movie_v1_movie_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateMovieRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateMovieRequest) ProtoMessage() {}
+
+func (x *CreateMovieRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateMovieRequest.ProtoReflect.Descriptor instead.
+func (*CreateMovieRequest) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateMovieRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *CreateMovieRequest) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *CreateMovieRequest) GetRuntime() int32 {
+	if x != nil {
+		return x.Runtime
+	}
+	return 0
+}
+
+func (x *CreateMovieRequest) GetGenres() []string {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+type UpdateMovieRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title   *string                `protobuf:"bytes,2,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	Year    *int32                 `protobuf:"varint,3,opt,name=year,proto3,oneof" json:"year,omitempty"`
+	Runtime *int32                 `protobuf:"varint,4,opt,name=runtime,proto3,oneof" json:"runtime,omitempty"`
+	// genres replaces the genres when it's set
+	Genres *Genres `protobuf:"bytes,5,opt,name=genres,proto3" json:"genres,omitempty"`
+	// version makes the update conditional like If-Match does, it only goes through at that version
+	Version       int32 `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMovieRequest) Reset() {
+	*x = UpdateMovieRequest{}
+	mi := &file_movie_v1_movie_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMovieRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMovieRequest) ProtoMessage() {}
+
+func (x *UpdateMovieRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMovieRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMovieRequest) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateMovieRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateMovieRequest) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+func (x *UpdateMovieRequest) GetYear() int32 {
+	if x != nil && x.Year != nil {
+		return *x.Year
+	}
+	return 0
+}
+
+func (x *UpdateMovieRequest) GetRuntime() int32 {
+	if x != nil && x.Runtime != nil {
+		return *x.Runtime
+	}
+	return 0
+}
+
+func (x *UpdateMovieRequest) GetGenres() *Genres {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+func (x *UpdateMovieRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Genres struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Genres) Reset() {
+	*x = Genres{}
+	mi := &file_movie_v1_movie_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Genres) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Genres) ProtoMessage() {}
+
+func (x *Genres) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Genres.ProtoReflect.Descriptor instead.
+func (*Genres) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Genres) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type DeleteMovieRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// version makes the delete conditional like If-Match does, it only goes through at that version
+	Version       int32 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMovieRequest) Reset() {
+	*x = DeleteMovieRequest{}
+	mi := &file_movie_v1_movie_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMovieRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMovieRequest) ProtoMessage() {}
+
+func (x *DeleteMovieRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMovieRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMovieRequest) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteMovieRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeleteMovieRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type DeleteMovieResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMovieResponse) Reset() {
+	*x = DeleteMovieResponse{}
+	mi := &file_movie_v1_movie_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMovieResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMovieResponse) ProtoMessage() {}
+
+func (x *DeleteMovieResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_movie_v1_movie_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMovieResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMovieResponse) Descriptor() ([]byte, []int) {
+	return file_movie_v1_movie_proto_rawDescGZIP(), []int{9}
+}
+
+var File_movie_v1_movie_proto protoreflect.FileDescriptor
+
+const file_movie_v1_movie_proto_rawDesc = "" +
+	"\n" +
+	"\x14movie/v1/movie.proto\x12\bmovie.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd7\x01\n" +
+	"\x05Movie\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x12\n" +
+	"\x04year\x18\x03 \x01(\x05R\x04year\x12\x18\n" +
+	"\aruntime\x18\x04 \x01(\x05R\aruntime\x12\x16\n" +
+	"\x06genres\x18\x05 \x03(\tR\x06genres\x12%\n" +
+	"\x0eaverage_rating\x18\x06 \x01(\x01R\raverageRating\x12!\n" +
+	"\frating_count\x18\a \x01(\x05R\vratingCount\x12\x18\n" +
+	"\aversion\x18\b \x01(\x05R\aversion\"\xa5\x04\n" +
+	"\x11ListMoviesRequest\x12\f\n" +
+	"\x01q\x18\x01 \x01(\tR\x01q\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
+	"\x06genres\x18\x03 \x03(\tR\x06genres\x12\x1d\n" +
+	"\n" +
+	"genres_any\x18\x04 \x03(\tR\tgenresAny\x12%\n" +
+	"\x0egenres_exclude\x18\x05 \x03(\tR\rgenresExclude\x12\x19\n" +
+	"\byear_min\x18\x06 \x01(\x05R\ayearMin\x12\x19\n" +
+	"\byear_max\x18\a \x01(\x05R\ayearMax\x12\x1f\n" +
+	"\vruntime_min\x18\b \x01(\x05R\n" +
+	"runtimeMin\x12\x1f\n" +
+	"\vruntime_max\x18\t \x01(\x05R\n" +
+	"runtimeMax\x12?\n" +
+	"\rcreated_after\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\fcreatedAfter\x12A\n" +
+	"\x0ecreated_before\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\rcreatedBefore\x12\x12\n" +
+	"\x04page\x18\f \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\r \x01(\x05R\bpageSize\x12\x12\n" +
+	"\x04sort\x18\x0e \x01(\tR\x04sort\x12\x16\n" +
+	"\x06cursor\x18\x0f \x01(\tR\x06cursor\x12\x1d\n" +
+	"\n" +
+	"skip_total\x18\x10 \x01(\bR\tskipTotal\x12\x16\n" +
+	"\x06fields\x18\x11 \x03(\tR\x06fields\"m\n" +
+	"\x12ListMoviesResponse\x12'\n" +
+	"\x06movies\x18\x01 \x03(\v2\x0f.movie.v1.MovieR\x06movies\x12.\n" +
+	"\bmetadata\x18\x02 \x01(\v2\x12.movie.v1.MetadataR\bmetadata\"\xed\x01\n" +
+	"\bMetadata\x12!\n" +
+	"\fcurrent_page\x18\x01 \x01(\x05R\vcurrentPage\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"first_page\x18\x03 \x01(\x05R\tfirstPage\x12\x1b\n" +
+	"\tlast_page\x18\x04 \x01(\x05R\blastPage\x12#\n" +
+	"\rtotal_records\x18\x05 \x01(\x05R\ftotalRecords\x12\x1f\n" +
+	"\vnext_cursor\x18\x06 \x01(\tR\n" +
+	"nextCursor\x12\x1f\n" +
+	"\vprev_cursor\x18\a \x01(\tR\n" +
+	"prevCursor\"9\n" +
+	"\x0fGetMovieRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x16\n" +
+	"\x06fields\x18\x02 \x03(\tR\x06fields\"p\n" +
+	"\x12CreateMovieRequest\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x12\n" +
+	"\x04year\x18\x02 \x01(\x05R\x04year\x12\x18\n" +
+	"\aruntime\x18\x03 \x01(\x05R\aruntime\x12\x16\n" +
+	"\x06genres\x18\x04 \x03(\tR\x06genres\"\xda\x01\n" +
+	"\x12UpdateMovieRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x19\n" +
+	"\x05title\x18\x02 \x01(\tH\x00R\x05title\x88\x01\x01\x12\x17\n" +
+	"\x04year\x18\x03 \x01(\x05H\x01R\x04year\x88\x01\x01\x12\x1d\n" +
+	"\aruntime\x18\x04 \x01(\x05H\x02R\aruntime\x88\x01\x01\x12(\n" +
+	"\x06genres\x18\x05 \x01(\v2\x10.movie.v1.GenresR\x06genres\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x05R\aversionB\b\n" +
+	"\x06_titleB\a\n" +
+	"\x05_yearB\n" +
+	"\n" +
+	"\b_runtime\" \n" +
+	"\x06Genres\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\">\n" +
+	"\x12DeleteMovieRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x05R\aversion\"\x15\n" +
+	"\x13DeleteMovieResponse2\xd7\x02\n" +
+	"\fMovieService\x12G\n" +
+	"\n" +
+	"ListMovies\x12\x1b.movie.v1.ListMoviesRequest\x1a\x1c.movie.v1.ListMoviesResponse\x126\n" +
+	"\bGetMovie\x12\x19.movie.v1.GetMovieRequest\x1a\x0f.movie.v1.Movie\x12<\n" +
+	"\vCreateMovie\x12\x1c.movie.v1.CreateMovieRequest\x1a\x0f.movie.v1.Movie\x12<\n" +
+	"\vUpdateMovie\x12\x1c.movie.v1.UpdateMovieRequest\x1a\x0f.movie.v1.Movie\x12J\n" +
+	"\vDeleteMovie\x12\x1c.movie.v1.DeleteMovieRequest\x1a\x1d.movie.v1.DeleteMovieResponseB(Z&movie-api/internal/rpc/moviev1;moviev1b\x06proto3"
+
+var (
+	file_movie_v1_movie_proto_rawDescOnce sync.Once
+	file_movie_v1_movie_proto_rawDescData []byte
+)
+
+func file_movie_v1_movie_proto_rawDescGZIP() []byte {
+	file_movie_v1_movie_proto_rawDescOnce.Do(func() {
+		file_movie_v1_movie_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_movie_v1_movie_proto_rawDesc), len(file_movie_v1_movie_proto_rawDesc)))
+	})
+	return file_movie_v1_movie_proto_rawDescData
+}
+
+var file_movie_v1_movie_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_movie_v1_movie_proto_goTypes = []any{
+	(*Movie)(nil),                 // 0: movie.v1.Movie
+	(*ListMoviesRequest)(nil),     // 1: movie.v1.ListMoviesRequest
+	(*ListMoviesResponse)(nil),    // 2: movie.v1.ListMoviesResponse
+	(*Metadata)(nil),              // 3: movie.v1.Metadata
+	(*GetMovieRequest)(nil),       // 4: movie.v1.GetMovieRequest
+	(*CreateMovieRequest)(nil),    // 5: movie.v1.CreateMovieRequest
+	(*UpdateMovieRequest)(nil),    // 6: movie.v1.UpdateMovieRequest
+	(*Genres)(nil),                // 7: movie.v1.Genres
+	(*DeleteMovieRequest)(nil),    // 8: movie.v1.DeleteMovieRequest
+	(*DeleteMovieResponse)(nil),   // 9: movie.v1.DeleteMovieResponse
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+}
+var file_movie_v1_movie_proto_depIdxs = []int32{
+	10, // 0: movie.v1.ListMoviesRequest.created_after:type_name -> google.protobuf.Timestamp
+	10, // 1: movie.v1.ListMoviesRequest.created_before:type_name -> google.protobuf.Timestamp
+	0,  // 2: movie.v1.ListMoviesResponse.movies:type_name -> movie.v1.Movie
+	3,  // 3: movie.v1.ListMoviesResponse.metadata:type_name -> movie.v1.Metadata
+	7,  // 4: movie.v1.UpdateMovieRequest.genres:type_name -> movie.v1.Genres
+	1,  // 5: movie.v1.MovieService.ListMovies:input_type -> movie.v1.ListMoviesRequest
+	4,  // 6: movie.v1.MovieService.GetMovie:input_type -> movie.v1.GetMovieRequest
+	5,  // 7: movie.v1.MovieService.CreateMovie:input_type -> movie.v1.CreateMovieRequest
+	6,  // 8: movie.v1.MovieService.UpdateMovie:input_type -> movie.v1.UpdateMovieRequest
+	8,  // 9: movie.v1.MovieService.DeleteMovie:input_type -> movie.v1.DeleteMovieRequest
+	2,  // 10: movie.v1.MovieService.ListMovies:output_type -> movie.v1.ListMoviesResponse
+	0,  // 11: movie.v1.MovieService.GetMovie:output_type -> movie.v1.Movie
+	0,  // 12: movie.v1.MovieService.CreateMovie:output_type -> movie.v1.Movie
+	0,  // 13: movie.v1.MovieService.UpdateMovie:output_type -> movie.v1.Movie
+	9,  // 14: movie.v1.MovieService.DeleteMovie:output_type -> movie.v1.DeleteMovieResponse
+	10, // [10:15] is the sub-list for method output_type
+	5,  // [5:10] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_movie_v1_movie_proto_init() }
+func file_movie_v1_movie_proto_init() {
+	if File_movie_v1_movie_proto != nil {
+		return
+	}
+	file_movie_v1_movie_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_movie_v1_movie_proto_rawDesc), len(file_movie_v1_movie_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_movie_v1_movie_proto_goTypes,
+		DependencyIndexes: file_movie_v1_movie_proto_depIdxs,
+		MessageInfos:      file_movie_v1_movie_proto_msgTypes,
+	}.Build()
+	File_movie_v1_movie_proto = out.File
+	file_movie_v1_movie_proto_goTypes = nil
+	file_movie_v1_movie_proto_depIdxs = nil
+}
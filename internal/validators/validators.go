@@ -9,13 +9,32 @@ var (
 		"^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
+// The codes of validation errors. Clients match on them rather than on the wording of the messages, so the
+// code of a check stays the same when its message is reworded.
+const (
+	CodeRequired     = "required"
+	CodeDuplicate    = "duplicate"
+	CodeTooLong      = "too_long"
+	CodeTooShort     = "too_short"
+	CodeOutOfRange   = "out_of_range"
+	CodeConflict     = "conflict"
+	CodeUnknownValue = "unknown_value"
+	CodeNotPermitted = "not_permitted"
+	CodePatchFailed  = "patch_failed"
+	CodeInvalid      = "invalid"
+)
+
 type Validators struct {
 	Errors map[string]string
+
+	// Codes holds the code of the error of each field in Errors
+	Codes map[string]string
 }
 
-func (v *Validators) AddErr(field, message string) {
+func (v *Validators) AddErr(field, code, message string) {
 	if _, exists := v.Errors[field]; !exists {
 		v.Errors[field] = message
+		v.Codes[field] = code
 	}
 }
 
@@ -23,9 +42,9 @@ func (v *Validators) IsValid() bool {
 	return len(v.Errors) == 0
 }
 
-func (v *Validators) Check(ok bool, field, message string) bool {
+func (v *Validators) Check(ok bool, field, code, message string) bool {
 	if !ok {
-		v.AddErr(field, message)
+		v.AddErr(field, code, message)
 	}
 	return true
 }
@@ -40,7 +59,7 @@ func Unique[T comparable](values []T) bool {
 }
 
 func New() *Validators {
-	return &Validators{Errors: map[string]string{}}
+	return &Validators{Errors: map[string]string{}, Codes: map[string]string{}}
 }
 
 func PermittedValues[T comparable](key T, list ...T) bool {
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    genres text[] NOT NULL DEFAULT '{}',
+    frequency text NOT NULL DEFAULT 'off',
+    last_digest_at timestamp(0) with time zone,
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE notification_preferences ADD CONSTRAINT notification_preferences_frequency_check CHECK (frequency IN ('daily', 'weekly', 'off'));
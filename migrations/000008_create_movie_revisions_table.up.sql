@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+    id bigserial PRIMARY KEY,
+    movie_id bigint NOT NULL,
+    version integer NOT NULL,
+    action text NOT NULL,
+    actor_id bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    changes jsonb NOT NULL DEFAULT '{}',
+    snapshot jsonb NOT NULL
+);
+
+ALTER TABLE movie_revisions ADD CONSTRAINT movie_revisions_action_check CHECK (action IN ('insert', 'update', 'delete'));
+ALTER TABLE movie_revisions ADD CONSTRAINT movie_revisions_movie_id_version_key UNIQUE (movie_id, version);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id bigserial PRIMARY KEY,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    rating smallint NOT NULL,
+    body text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+ALTER TABLE reviews ADD CONSTRAINT reviews_rating_check CHECK (rating BETWEEN 1 AND 10);
+ALTER TABLE reviews ADD CONSTRAINT reviews_movie_id_user_id_key UNIQUE (movie_id, user_id);
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS average_rating numeric(4, 2) NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS rating_count integer NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS movies_average_rating_idx ON movies (average_rating);
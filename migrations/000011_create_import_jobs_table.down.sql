@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS movies_title_year_idx;
+
+DROP TABLE IF EXISTS import_jobs;
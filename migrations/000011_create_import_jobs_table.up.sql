@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id bigserial PRIMARY KEY,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    finished_at timestamp(0) with time zone,
+    mode text NOT NULL,
+    dry_run boolean NOT NULL DEFAULT false,
+    status text NOT NULL,
+    total_rows integer NOT NULL DEFAULT 0,
+    valid_rows integer NOT NULL DEFAULT 0,
+    inserted integer NOT NULL DEFAULT 0,
+    updated integer NOT NULL DEFAULT 0,
+    row_errors jsonb NOT NULL DEFAULT '[]',
+    failure text NOT NULL DEFAULT ''
+);
+
+ALTER TABLE import_jobs ADD CONSTRAINT import_jobs_mode_check CHECK (mode IN ('insert', 'upsert'));
+ALTER TABLE import_jobs ADD CONSTRAINT import_jobs_status_check CHECK (status IN ('pending', 'running', 'completed', 'failed'));
+
+CREATE INDEX IF NOT EXISTS movies_title_year_idx ON movies (title, year);
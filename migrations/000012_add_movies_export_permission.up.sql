@@ -0,0 +1 @@
+INSERT INTO permissions (code) VALUES ('movies:export') ON CONFLICT DO NOTHING;
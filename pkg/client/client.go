@@ -0,0 +1,234 @@
+// Package client is a typed client of the movie API. Responses are decoded into the types of the API itself,
+// errors into *Error whichever format the server writes them in.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	maxBackoff        = 30 * time.Second
+)
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+
+	mu    sync.RWMutex
+	token string
+
+	Movies *MovieService
+	People *PersonService
+	Users  *UserService
+	Tokens *TokenService
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with the given client instead of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates the requests with a bearer token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithRetries sets how many times a rate limited request is retried, and how long to wait before the first
+// retry when the server doesn't say with Retry-After. The wait doubles on every retry.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New returns a client of the API served at baseURL, like http://localhost:4000
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL %q must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.Movies = &MovieService{client: c}
+	c.People = &PersonService{client: c}
+	c.Users = &UserService{client: c}
+	c.Tokens = &TokenService{client: c}
+
+	return c, nil
+}
+
+// SetToken changes the bearer token of the next requests, an empty token makes them anonymous
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+}
+
+func (c *Client) bearer() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.token
+}
+
+// request describes a call to the API, dst receives the members of the response envelope
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   any
+	dst    any
+}
+
+// do sends the request, retrying it while it's rate limited, and decodes the response envelope into dst
+func (c *Client) do(ctx context.Context, req request) error {
+	var body []byte
+
+	if req.body != nil {
+		var err error
+
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		r, err := http.NewRequestWithContext(ctx, req.method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		for key, values := range req.header {
+			r.Header[key] = values
+		}
+
+		r.Header.Set("Accept", "application/json")
+		if body != nil {
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		if token := c.bearer(); token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res, err := c.httpClient.Do(r)
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests && attempt < c.maxRetries {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+
+			err = sleep(ctx, c.retryAfter(res, attempt))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		defer res.Body.Close()
+
+		if res.StatusCode >= http.StatusBadRequest {
+			return decodeError(res)
+		}
+
+		if req.dst != nil && res.StatusCode != http.StatusNoContent {
+			err = json.NewDecoder(res.Body).Decode(req.dst)
+			if err != nil {
+				return fmt.Errorf("client: decoding the response of %s %s: %w", req.method, req.path, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// retryAfter is the wait the server asks for with Retry-After, in seconds or as a date, or else the backoff
+// of the attempt
+func (c *Client) retryAfter(res *http.Response, attempt int) time.Duration {
+	value := res.Header.Get("Retry-After")
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return min(c.backoff<<attempt, maxBackoff)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// addCSV sets a comma separated query parameter when there are values
+func addCSV(query url.Values, key string, values []string) {
+	if len(values) != 0 {
+		query.Set(key, strings.Join(values, ","))
+	}
+}
+
+func addInt(query url.Values, key string, value int) {
+	if value != 0 {
+		query.Set(key, strconv.Itoa(value))
+	}
+}
+
+func addString(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	c := &Client{backoff: 100 * time.Millisecond}
+
+	tests := []struct {
+		header  string
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"3", 0, 3 * time.Second, 3 * time.Second},
+		{"0", 2, 0, 0},
+		{"", 0, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"", 2, 400 * time.Millisecond, 400 * time.Millisecond},
+		{"", 20, maxBackoff, maxBackoff},
+		{"soon", 1, 200 * time.Millisecond, 200 * time.Millisecond},
+		{time.Now().Add(5 * time.Second).UTC().Format(http.TimeFormat), 0, 3 * time.Second, 5 * time.Second},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		res := &http.Response{Header: http.Header{}}
+		res.Header.Set("Retry-After", tt.header)
+
+		if got := c.retryAfter(res, tt.attempt); got < tt.min || got > tt.max {
+			t.Errorf("Retry-After %q, attempt %d: got %s, want between %s and %s", tt.header, tt.attempt, got, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Error is an error response of the API. Servers writing problem details fill every field, the ones running
+// with -error-format=legacy only give a message or the messages of the invalid fields.
+type Error struct {
+	StatusCode int          `json:"status"`
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	Instance   string       `json:"instance"`
+	Code       string       `json:"code"`
+	Fields     []FieldError `json:"errors"`
+}
+
+// FieldError is a field a request was rejected for
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	message := e.Detail
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("movie api: %d %s", e.StatusCode, message)
+	}
+
+	var fields []string
+	for _, field := range e.Fields {
+		fields = append(fields, field.Field+" "+field.Message)
+	}
+
+	return fmt.Sprintf("movie api: %d %s: %s", e.StatusCode, message, strings.Join(fields, "; "))
+}
+
+// IsNotFound reports whether err is a 404 of the API
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is an edit conflict or a failed If-Match, the resource changed since it was read
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict) || hasStatus(err, http.StatusPreconditionFailed)
+}
+
+// IsValidation reports whether err rejects invalid fields, the Fields of the *Error say which
+func IsValidation(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && len(apiErr.Fields) != 0
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// decodeError reads the error of a response, as problem details or in the legacy {"error": ...} envelope
+func decodeError(res *http.Response) error {
+	apiErr := &Error{StatusCode: res.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return apiErr
+	}
+
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/problem+json") {
+		json.Unmarshal(body, apiErr)
+		apiErr.StatusCode = res.StatusCode
+		return apiErr
+	}
+
+	var legacy struct {
+		Error json.RawMessage `json:"error"`
+	}
+
+	if json.Unmarshal(body, &legacy) != nil || legacy.Error == nil {
+		apiErr.Detail = strings.TrimSpace(string(body))
+		return apiErr
+	}
+
+	// a message, or the messages of invalid fields
+	var message string
+	var fields map[string]string
+
+	switch {
+	case json.Unmarshal(legacy.Error, &message) == nil:
+		apiErr.Detail = message
+	case json.Unmarshal(legacy.Error, &fields) == nil:
+		for field, message := range fields {
+			apiErr.Fields = append(apiErr.Fields, FieldError{Field: field, Message: message})
+		}
+		sort.Slice(apiErr.Fields, func(i, j int) bool { return apiErr.Fields[i].Field < apiErr.Fields[j].Field })
+	}
+
+	return apiErr
+}
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"movie-api/internal/data"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type (
+	Movie    = data.Movies
+	Metadata = data.Metadata
+	Runtime  = data.Runtime
+	Credit   = data.Credit
+)
+
+type MovieService struct {
+	client *Client
+}
+
+// MovieFilters are the filters, sorting and paging of a movie listing. Zero values are left out.
+type MovieFilters struct {
+	Search        string
+	Title         string
+	Genres        []string
+	GenresAny     []string
+	GenresExclude []string
+	YearMin       int
+	YearMax       int
+	RuntimeMin    int
+	RuntimeMax    int
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	Page      int
+	PageSize  int
+	Sort      string
+	Cursor    string
+	SkipTotal bool
+
+	Fields        []string
+	Include       []string
+	RuntimeFormat string
+}
+
+func (f MovieFilters) query() url.Values {
+	query := url.Values{}
+
+	addString(query, "q", f.Search)
+	addString(query, "title", f.Title)
+	addCSV(query, "genres", f.Genres)
+	addCSV(query, "genres_any", f.GenresAny)
+	addCSV(query, "genres_exclude", f.GenresExclude)
+	addInt(query, "year_min", f.YearMin)
+	addInt(query, "year_max", f.YearMax)
+	addInt(query, "runtime_min", f.RuntimeMin)
+	addInt(query, "runtime_max", f.RuntimeMax)
+
+	if !f.CreatedAfter.IsZero() {
+		query.Set("created_after", f.CreatedAfter.Format(time.RFC3339))
+	}
+
+	if !f.CreatedBefore.IsZero() {
+		query.Set("created_before", f.CreatedBefore.Format(time.RFC3339))
+	}
+
+	addInt(query, "page", f.Page)
+	addInt(query, "page_size", f.PageSize)
+	addString(query, "sort", f.Sort)
+	addString(query, "cursor", f.Cursor)
+
+	if f.SkipTotal {
+		query.Set("include_total", "false")
+	}
+
+	addCSV(query, "fields", f.Fields)
+	addCSV(query, "include", f.Include)
+	addString(query, "runtime_format", f.RuntimeFormat)
+
+	return query
+}
+
+// MovieInput is the movie to create
+type MovieInput struct {
+	Title   string   `json:"title"`
+	Year    int32    `json:"year"`
+	Runtime Runtime  `json:"runtime"`
+	Genres  []string `json:"genres"`
+}
+
+// MovieUpdate holds the fields to change, nil ones are left alone. With a Version the update only goes
+// through when the movie is still at that version.
+type MovieUpdate struct {
+	Title   *string  `json:"title,omitempty"`
+	Year    *int32   `json:"year,omitempty"`
+	Runtime *Runtime `json:"runtime,omitempty"`
+	Genres  []string `json:"genres,omitempty"`
+
+	Version int32 `json:"-"`
+}
+
+// List reads a page of the movies matching the filters
+func (s *MovieService) List(ctx context.Context, filters MovieFilters) ([]*Movie, Metadata, error) {
+	var env struct {
+		Movies   []*Movie `json:"movies"`
+		Metadata Metadata `json:"metadata"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/v1/movies", query: filters.query(), dst: &env})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return env.Movies, env.Metadata, nil
+}
+
+// Iterate walks every movie matching the filters, following the cursors of the pages. Page and Cursor are
+// ignored, the walk always starts at the first movie.
+func (s *MovieService) Iterate(ctx context.Context, filters MovieFilters) *MovieIterator {
+	filters.Page = 0
+	filters.Cursor = ""
+	filters.SkipTotal = true
+
+	return &MovieIterator{ctx: ctx, service: s, filters: filters}
+}
+
+// MovieIterator walks the movies of a listing a page at a time
+//
+//	it := c.Movies.Iterate(ctx, filters)
+//	for it.Next() {
+//		movie := it.Movie()
+//	}
+//	if it.Err() != nil {
+//	}
+type MovieIterator struct {
+	ctx     context.Context
+	service *MovieService
+	filters MovieFilters
+
+	page    []*Movie
+	current *Movie
+	done    bool
+	err     error
+}
+
+// Next moves to the next movie, reading the next page when needed. It returns false at the end or on an error.
+func (it *MovieIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		movies, metadata, err := it.service.List(it.ctx, it.filters)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.page = movies
+		it.filters.Cursor = metadata.NextCursor
+		it.done = metadata.NextCursor == ""
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *MovieIterator) Movie() *Movie {
+	return it.current
+}
+
+func (it *MovieIterator) Err() error {
+	return it.err
+}
+
+// Get reads a movie, include embeds relations like credits into it
+func (s *MovieService) Get(ctx context.Context, id int64, include ...string) (*Movie, error) {
+	query := url.Values{}
+	addCSV(query, "include", include)
+
+	var env struct {
+		Movie *Movie `json:"movie"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodGet, path: moviePath(id), query: query, dst: &env})
+	if err != nil {
+		return nil, err
+	}
+
+	return env.Movie, nil
+}
+
+// Create creates a movie. A non empty idempotency key makes retries of the call return the movie created
+// the first time.
+func (s *MovieService) Create(ctx context.Context, input MovieInput, idempotencyKey string) (*Movie, error) {
+	header := http.Header{}
+	if idempotencyKey != "" {
+		header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	var env struct {
+		Movie *Movie `json:"movie"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/v1/movies", header: header, body: input, dst: &env})
+	if err != nil {
+		return nil, err
+	}
+
+	return env.Movie, nil
+}
+
+// Update changes the fields set in update, IsConflict reports whether it lost against another update
+func (s *MovieService) Update(ctx context.Context, id int64, update MovieUpdate) (*Movie, error) {
+	header := http.Header{}
+	if update.Version != 0 {
+		header.Set("If-Match", movieETag(id, update.Version))
+	}
+
+	var env struct {
+		Movie *Movie `json:"movie"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodPatch, path: moviePath(id), header: header, body: update, dst: &env})
+	if err != nil {
+		return nil, err
+	}
+
+	return env.Movie, nil
+}
+
+// Delete deletes a movie, only at the given version unless it's 0
+func (s *MovieService) Delete(ctx context.Context, id int64, version int32) error {
+	header := http.Header{}
+	if version != 0 {
+		header.Set("If-Match", movieETag(id, version))
+	}
+
+	return s.client.do(ctx, request{method: http.MethodDelete, path: moviePath(id), header: header})
+}
+
+func moviePath(id int64) string {
+	return "/v1/movies/" + strconv.FormatInt(id, 10)
+}
+
+// movieETag is the tag of a movie at a version, which the API takes in If-Match next to the ETag it sends
+func movieETag(id int64, version int32) string {
+	return fmt.Sprintf(`"%d-%d"`, id, version)
+}
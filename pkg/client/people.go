@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"movie-api/internal/data"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type Person = data.Person
+
+type PersonService struct {
+	client *Client
+}
+
+// PersonFilters are the filters, sorting and paging of a people listing. Zero values are left out.
+type PersonFilters struct {
+	Name     string
+	Page     int
+	PageSize int
+	Sort     string
+}
+
+// PersonInput is the person to create
+type PersonInput struct {
+	Name      string `json:"name"`
+	Biography string `json:"biography,omitempty"`
+}
+
+func (s *PersonService) List(ctx context.Context, filters PersonFilters) ([]*Person, Metadata, error) {
+	query := url.Values{}
+	addString(query, "name", filters.Name)
+	addInt(query, "page", filters.Page)
+	addInt(query, "page_size", filters.PageSize)
+	addString(query, "sort", filters.Sort)
+
+	var env struct {
+		People   []*Person `json:"people"`
+		Metadata Metadata  `json:"metadata"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/v1/people", query: query, dst: &env})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return env.People, env.Metadata, nil
+}
+
+func (s *PersonService) Get(ctx context.Context, id int64) (*Person, error) {
+	var env struct {
+		Person *Person `json:"person"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodGet, path: personPath(id), dst: &env})
+	if err != nil {
+		return nil, err
+	}
+
+	return env.Person, nil
+}
+
+func (s *PersonService) Create(ctx context.Context, input PersonInput) (*Person, error) {
+	var env struct {
+		Person *Person `json:"person"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/v1/people", body: input, dst: &env})
+	if err != nil {
+		return nil, err
+	}
+
+	return env.Person, nil
+}
+
+func (s *PersonService) Delete(ctx context.Context, id int64) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: personPath(id)})
+}
+
+func personPath(id int64) string {
+	return "/v1/people/" + strconv.FormatInt(id, 10)
+}
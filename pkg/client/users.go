@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"movie-api/internal/data"
+	"net/http"
+)
+
+type (
+	User  = data.User
+	Token = data.Token
+)
+
+type UserService struct {
+	client *Client
+}
+
+// Register creates a user, the API mails them the token Activate takes
+func (s *UserService) Register(ctx context.Context, name, email, password string) (*User, error) {
+	input := struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{name, email, password}
+
+	var env struct {
+		User *User `json:"user"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/v1/users", body: input, dst: &env})
+	if err != nil {
+		return nil, err
+	}
+
+	return env.User, nil
+}
+
+// Activate activates the user an activation token was sent to
+func (s *UserService) Activate(ctx context.Context, token string) (*User, error) {
+	input := struct {
+		Token string `json:"token"`
+	}{token}
+
+	var env struct {
+		User *User `json:"user"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodPut, path: "/v1/users/activated", body: input, dst: &env})
+	if err != nil {
+		return nil, err
+	}
+
+	return env.User, nil
+}
+
+type TokenService struct {
+	client *Client
+}
+
+// Authenticate creates an authentication token and sets it on the client for the next requests
+func (s *TokenService) Authenticate(ctx context.Context, email, password string) (*Token, error) {
+	input := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{email, password}
+
+	var env struct {
+		Token *Token `json:"token"`
+	}
+
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/v1/tokens/authentication", body: input, dst: &env})
+	if err != nil {
+		return nil, err
+	}
+
+	s.client.SetToken(env.Token.Plaintext)
+
+	return env.Token, nil
+}
+
+// RequestActivation has a new activation token mailed to a user who isn't activated yet
+func (s *TokenService) RequestActivation(ctx context.Context, email string) error {
+	input := struct {
+		Email string `json:"email"`
+	}{email}
+
+	return s.client.do(ctx, request{method: http.MethodPost, path: "/v1/tokens/activation", body: input})
+}
+
+// Revoke revokes every authentication token of the authenticated user, the one of the client included
+func (s *TokenService) Revoke(ctx context.Context) error {
+	err := s.client.do(ctx, request{method: http.MethodDelete, path: "/v1/tokens/authentication"})
+	if err != nil {
+		return err
+	}
+
+	s.client.SetToken("")
+	return nil
+}